@@ -3,8 +3,12 @@ module github.com/weaponry/go-instrumenting
 go 1.14
 
 require (
+	github.com/go-chi/chi/v5 v5.0.7
 	github.com/go-redis/redis/v7 v7.4.0
+	github.com/gorilla/mux v1.8.0
+	github.com/jackc/pgconn v1.6.1
 	github.com/jackc/pgx/v4 v4.7.0
 	github.com/prometheus/client_golang v1.7.1
+	github.com/prometheus/client_model v0.2.0
 	github.com/stretchr/testify v1.6.1
 )
@@ -0,0 +1,53 @@
+// Package chiresolver resolves paths of requests routed by go-chi/chi for HTTP metrics.
+package chiresolver
+
+import (
+	"github.com/go-chi/chi/v5"
+	httpmetrics "github.com/weaponry/go-instrumenting/metrics/http"
+	"net/http"
+)
+
+type resolver struct {
+	routes chi.Routes
+}
+
+// New returns a PathResolver which uses chi route patterns as paths of requests. Pattern of the routing context is
+// used when the middleware is installed with router.Use(), otherwise the request is matched against routes, which
+// could be nil if the middleware is always used inside the router. Requests which have not been routed yet (e.g. when
+// counted as in flight) are matched against the router of the routing context.
+func New(routes chi.Routes) httpmetrics.PathResolver {
+	return resolver{routes: routes}
+}
+
+func (c resolver) ResolvePath(r *http.Request) (string, bool) {
+	rctx := chi.RouteContext(r.Context())
+	if rctx != nil {
+		if pattern := rctx.RoutePattern(); pattern != "" {
+			return pattern, true
+		}
+	}
+
+	if c.routes != nil {
+		return matchRoute(c.routes, r.Method, r.URL.Path)
+	}
+
+	// Middlewares installed with router.Use() run before routing, the router is already known though.
+	if rctx != nil && rctx.Routes != nil {
+		path := rctx.RoutePath
+		if path == "" {
+			path = r.URL.Path
+		}
+		return matchRoute(rctx.Routes, r.Method, path)
+	}
+
+	return "", false
+}
+
+// matchRoute returns pattern of the route matching method and path.
+func matchRoute(routes chi.Routes, method, path string) (string, bool) {
+	rctx := chi.NewRouteContext()
+	if !routes.Match(rctx, method, path) {
+		return "", false
+	}
+	return rctx.RoutePattern(), true
+}
@@ -0,0 +1,105 @@
+package chiresolver_test
+
+import (
+	"github.com/go-chi/chi/v5"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/stretchr/testify/assert"
+	"github.com/weaponry/go-instrumenting/metrics"
+	httpmetrics "github.com/weaponry/go-instrumenting/metrics/http"
+	"github.com/weaponry/go-instrumenting/metrics/http/chiresolver"
+	"github.com/weaponry/go-instrumenting/metrics/metricstest"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestResolvePath(t *testing.T) {
+	noop := func(w http.ResponseWriter, r *http.Request) {}
+
+	router := chi.NewRouter()
+	router.Get("/users/{id}", noop)
+	router.Route("/orgs/{org}", func(r chi.Router) {
+		r.Get("/repos/{repo}", noop)
+	})
+
+	testCases := []struct {
+		name    string
+		method  string
+		target  string
+		expPath string
+		expOk   bool
+	}{
+		{
+			name:   "Chi should resolve route pattern.",
+			method: http.MethodGet, target: "/users/12345",
+			expPath: "/users/{id}", expOk: true,
+		},
+		{
+			name:   "Chi should resolve route pattern of sub-router.",
+			method: http.MethodGet, target: "/orgs/acme/repos/website",
+			expPath: "/orgs/{org}/repos/{repo}", expOk: true,
+		},
+		{
+			name:   "Chi should not resolve unknown path.",
+			method: http.MethodGet, target: "/unknown",
+			expOk: false,
+		},
+	}
+
+	resolver := chiresolver.New(router)
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			path, ok := resolver.ResolvePath(httptest.NewRequest(tc.method, tc.target, nil))
+			assert.Equal(t, tc.expOk, ok)
+			if tc.expOk {
+				assert.Equal(t, tc.expPath, path)
+			}
+		})
+	}
+}
+
+func TestMiddlewarePathResolver(t *testing.T) {
+	metricRecorder := httpmetrics.NewHttpRecorder("test-app", httpmetrics.Config{})
+	defer metricRecorder.Unregister()
+
+	router := chi.NewRouter()
+	router.Use(httpmetrics.Middleware(metricRecorder, httpmetrics.WithPathResolver(chiresolver.New(nil))))
+	router.Get("/users/{id}", func(w http.ResponseWriter, r *http.Request) {})
+
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/users/1", nil))
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/users/2", nil))
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/unknown/3", nil))
+
+	// Get the metrics handler and serve.
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest("GET", "/metrics", nil)
+	promhttp.HandlerFor(prometheus.DefaultGatherer, promhttp.HandlerOpts{}).ServeHTTP(rec, req)
+
+	body, _ := ioutil.ReadAll(rec.Result().Body)
+	assert.Contains(t, string(body), `app_http_requests_total{application="test-app",method="GET",path="/users/{id}",status="200"} 2`)
+	assert.Contains(t, string(body), `app_http_requests_total{application="test-app",method="GET",path="<unmatched>",status="404"} 1`)
+}
+
+func TestMiddlewarePathResolverInFlight(t *testing.T) {
+	registry := prometheus.NewRegistry()
+	metricRecorder := httpmetrics.NewHttpRecorder("test-app", httpmetrics.Config{Options: metrics.Options{Registerer: registry}})
+
+	router := chi.NewRouter()
+	router.Use(httpmetrics.Middleware(metricRecorder, httpmetrics.WithPathResolver(chiresolver.New(nil))))
+	router.Get("/users/{id}", func(w http.ResponseWriter, r *http.Request) {
+		// Requests in flight should be labelled by route pattern although the middleware runs before routing.
+		metricstest.AssertGauge(t, registry, "app_http_requests_in_flight", prometheus.Labels{"path": "/users/{id}"}, 1)
+	})
+	router.Route("/orgs/{org}", func(r chi.Router) {
+		r.Get("/members/{id}", func(w http.ResponseWriter, r *http.Request) {
+			metricstest.AssertGauge(t, registry, "app_http_requests_in_flight", prometheus.Labels{"path": "/orgs/{org}/members/{id}"}, 1)
+		})
+	})
+
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/users/1", nil))
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/orgs/acme/members/2", nil))
+
+	assert.Equal(t, []string{"/orgs/{org}/members/{id}", "/users/{id}"}, metricstest.LabelValues(t, registry, "app_http_requests_in_flight", "path"))
+}
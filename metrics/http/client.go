@@ -0,0 +1,146 @@
+package http
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/weaponry/go-instrumenting/metrics"
+	"time"
+)
+
+const (
+	labelHost       = "host"
+	labelOperation  = "operation"
+	labelError      = "error"
+	labelPhase      = "phase"
+	labelConnection = "connection"
+)
+
+type clientRecorder struct {
+	Registry                       prometheus.Registerer
+	gatherer                       prometheus.Gatherer
+	HttpRequestsTotal              *prometheus.CounterVec
+	HttpRequestsDurationsHistogram *prometheus.HistogramVec
+	HttpResponseSizeHistogram      *prometheus.HistogramVec
+	HttpPhaseDurationsHistogram    *prometheus.HistogramVec
+	HttpConnectionsTotal           *prometheus.CounterVec
+}
+
+// NewHttpClientRecorder creates recorder of outbound HTTP requests and registers its metrics, it panics if
+// registration fails.
+func NewHttpClientRecorder(appName string, config Config) metrics.HttpClientRecorder {
+	r, err := RegisterHttpClientRecorder(appName, config)
+	if err != nil {
+		panic(err)
+	}
+	return r
+}
+
+// RegisterHttpClientRecorder creates recorder of outbound HTTP requests and registers its metrics. Configuration is
+// shared with HTTP recorder, but subsystem defaults to 'http_client'. Metrics which have been already registered by
+// another recorder with the same configuration are reused.
+func RegisterHttpClientRecorder(appName string, config Config) (metrics.HttpClientRecorder, error) {
+	config.SetDefaults("http_client")
+	config.defaults()
+
+	if err := config.Validate(labelHost, labelOperation, labelMethod, labelStatus, labelError, labelPhase, labelConnection); err != nil {
+		return nil, err
+	}
+
+	constLabels := config.Labels(appName)
+
+	r := &clientRecorder{
+		HttpRequestsTotal: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace:   config.Namespace,
+			Subsystem:   config.Subsystem,
+			Name:        "requests_total",
+			Help:        "The total number of outbound requests.",
+			ConstLabels: constLabels,
+		}, []string{labelHost, labelOperation, labelMethod, labelStatus, labelError}),
+
+		HttpRequestsDurationsHistogram: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace:   config.Namespace,
+			Subsystem:   config.Subsystem,
+			Name:        "request_duration_seconds",
+			Help:        "The latency of the outbound HTTP requests including reading of the response body.",
+			Buckets:     config.DurationBuckets,
+			ConstLabels: constLabels,
+		}, []string{labelHost, labelOperation, labelMethod, labelStatus}),
+
+		HttpResponseSizeHistogram: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace:   config.Namespace,
+			Subsystem:   config.Subsystem,
+			Name:        "response_size_bytes",
+			Help:        "The size of the responses read by HTTP client.",
+			Buckets:     config.SizeBuckets,
+			ConstLabels: constLabels,
+		}, []string{labelHost, labelOperation, labelMethod, labelStatus}),
+
+		HttpPhaseDurationsHistogram: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace:   config.Namespace,
+			Subsystem:   config.Subsystem,
+			Name:        "phase_duration_seconds",
+			Help:        "The latency of the phases of outbound HTTP requests.",
+			Buckets:     config.DurationBuckets,
+			ConstLabels: constLabels,
+		}, []string{labelHost, labelPhase}),
+
+		HttpConnectionsTotal: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace:   config.Namespace,
+			Subsystem:   config.Subsystem,
+			Name:        "connections_total",
+			Help:        "The total number of new and reused connections obtained for outbound requests.",
+			ConstLabels: constLabels,
+		}, []string{labelHost, labelConnection}),
+	}
+
+	r.Registry = config.Registerer
+	r.gatherer = config.Gatherer
+
+	err := metrics.Register(r.Registry,
+		&r.HttpRequestsTotal,
+		metrics.Histogram(&r.HttpRequestsDurationsHistogram, config.DurationBuckets),
+		metrics.Histogram(&r.HttpResponseSizeHistogram, config.SizeBuckets),
+		metrics.Histogram(&r.HttpPhaseDurationsHistogram, config.DurationBuckets),
+		&r.HttpConnectionsTotal,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return r, nil
+}
+
+// Collect updates metrics using passed properties, size is not observed if response has not been received
+func (r clientRecorder) Collect(props metrics.HTTPClientReqProperties, duration time.Duration, bytesRead int) {
+	r.HttpRequestsTotal.WithLabelValues(props.Host, props.Operation, props.Method, props.Code, props.Error).Inc()
+	r.HttpRequestsDurationsHistogram.WithLabelValues(props.Host, props.Operation, props.Method, props.Code).Observe(duration.Seconds())
+
+	if props.Code != valueNone {
+		r.HttpResponseSizeHistogram.WithLabelValues(props.Host, props.Operation, props.Method, props.Code).Observe(float64(bytesRead))
+	}
+}
+
+// CollectPhase updates metrics of request phases using passed properties
+func (r clientRecorder) CollectPhase(props metrics.HTTPClientPhaseProperties, duration time.Duration) {
+	r.HttpPhaseDurationsHistogram.WithLabelValues(props.Host, props.Phase).Observe(duration.Seconds())
+}
+
+// CollectConnection updates metrics of connections using passed properties
+func (r clientRecorder) CollectConnection(props metrics.HTTPClientConnProperties) {
+	r.HttpConnectionsTotal.WithLabelValues(props.Host, props.Connection).Inc()
+}
+
+// Gatherer returns the gatherer used for gathering recorded metrics.
+func (r clientRecorder) Gatherer() prometheus.Gatherer {
+	return r.gatherer
+}
+
+// Unregister ...
+func (r clientRecorder) Unregister() {
+	metrics.Unregister(r.Registry,
+		r.HttpRequestsTotal,
+		r.HttpRequestsDurationsHistogram,
+		r.HttpResponseSizeHistogram,
+		r.HttpPhaseDurationsHistogram,
+		r.HttpConnectionsTotal,
+	)
+}
@@ -1,28 +1,39 @@
 package http
 
 import (
+	"fmt"
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/weaponry/go-instrumenting/metrics"
 	"time"
 )
 
 const (
-	labelApp    = "application"
 	labelPath   = "path"
 	labelMethod = "method"
 	labelStatus = "status"
+	labelKind   = "kind"
+
+	// statusHijacked is a value of the status label used for requests which connections have been hijacked.
+	statusHijacked = "hijacked"
+	// statusAborted is a value of the status label used for requests aborted with http.ErrAbortHandler.
+	statusAborted = "aborted"
 )
 
 type Config struct {
+	// Options describe names of metrics, constant labels and the registerer, by default metrics are named as
+	// 'app_<subsystem>_*' and registered with prometheus.DefaultRegisterer.
+	metrics.Options
 	// DurationBuckets are the buckets used by Prometheus for the HTTP request duration metrics,
 	// by default uses Prometheus default buckets (from 5ms to 10s).
 	DurationBuckets []float64
-	// SizeBuckets are the buckets used by Prometheus for the HTTP response size metrics,
+	// SizeBuckets are the buckets used by Prometheus for the HTTP request and response size metrics,
 	// by default uses a exponential buckets from 100B to 1GB.
 	SizeBuckets []float64
 }
 
 func (c *Config) defaults() {
+	c.SetDefaults("http")
+
 	if len(c.DurationBuckets) == 0 {
 		c.DurationBuckets = prometheus.DefBuckets
 	}
@@ -34,51 +45,121 @@ func (c *Config) defaults() {
 
 type recorder struct {
 	Registry                       prometheus.Registerer
+	gatherer                       prometheus.Gatherer
 	HttpRequestsTotal              *prometheus.CounterVec
 	HttpRequestsDurationsHistogram *prometheus.HistogramVec
 	HttpResponseSizeHistogram      *prometheus.HistogramVec
+	HttpRequestSizeHistogram       *prometheus.HistogramVec
+	HttpRequestsInFlight           *prometheus.GaugeVec
+	HttpRequestsInFlightMax        *watermark
+	HttpPanicsTotal                *prometheus.CounterVec
 }
 
+// NewHttpRecorder creates HTTP recorder and registers its metrics, it panics if registration fails.
 func NewHttpRecorder(appName string, config Config) metrics.HttpRecorder {
+	r, err := RegisterHttpRecorder(appName, config)
+	if err != nil {
+		panic(err)
+	}
+	return r
+}
+
+// RegisterHttpRecorder creates HTTP recorder and registers its metrics. Metrics which have been already registered
+// by another recorder with the same configuration are reused.
+func RegisterHttpRecorder(appName string, config Config) (metrics.HttpRecorder, error) {
 	config.defaults()
 
+	if err := config.Validate(labelPath, labelMethod, labelStatus, labelKind); err != nil {
+		return nil, err
+	}
+
+	constLabels := config.Labels(appName)
+
 	r := &recorder{
 		HttpRequestsTotal: prometheus.NewCounterVec(prometheus.CounterOpts{
-			Namespace:   "app",
-			Subsystem:   "http",
+			Namespace:   config.Namespace,
+			Subsystem:   config.Subsystem,
 			Name:        "requests_total",
 			Help:        "The total number of processed requests.",
-			ConstLabels: map[string]string{labelApp: appName},
+			ConstLabels: constLabels,
 		}, []string{labelPath, labelMethod, labelStatus}),
 
 		HttpRequestsDurationsHistogram: prometheus.NewHistogramVec(prometheus.HistogramOpts{
-			Namespace:   "app",
-			Subsystem:   "http",
+			Namespace:   config.Namespace,
+			Subsystem:   config.Subsystem,
 			Name:        "request_duration_seconds",
 			Help:        "The latency of the HTTP requests.",
 			Buckets:     config.DurationBuckets,
-			ConstLabels: map[string]string{labelApp: appName},
+			ConstLabels: constLabels,
 		}, []string{labelPath, labelMethod, labelStatus}),
 
 		HttpResponseSizeHistogram: prometheus.NewHistogramVec(prometheus.HistogramOpts{
-			Namespace:   "app",
-			Subsystem:   "http",
+			Namespace:   config.Namespace,
+			Subsystem:   config.Subsystem,
 			Name:        "response_size_bytes",
 			Help:        "The size of the HTTP responses.",
 			Buckets:     config.SizeBuckets,
-			ConstLabels: map[string]string{labelApp: appName},
+			ConstLabels: constLabels,
 		}, []string{labelPath, labelMethod, labelStatus}),
+
+		HttpRequestSizeHistogram: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace:   config.Namespace,
+			Subsystem:   config.Subsystem,
+			Name:        "request_size_bytes",
+			Help:        "The size of the HTTP request bodies read by handlers.",
+			Buckets:     config.SizeBuckets,
+			ConstLabels: constLabels,
+		}, []string{labelPath, labelMethod, labelStatus}),
+
+		HttpRequestsInFlight: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace:   config.Namespace,
+			Subsystem:   config.Subsystem,
+			Name:        "requests_in_flight",
+			Help:        "The number of requests currently being processed.",
+			ConstLabels: constLabels,
+		}, []string{labelPath, labelMethod}),
+
+		HttpRequestsInFlightMax: newWatermark(prometheus.NewDesc(
+			prometheus.BuildFQName(config.Namespace, config.Subsystem, "requests_in_flight_max"),
+			"The maximum number of requests processed concurrently since the previous scrape.",
+			nil, constLabels,
+		)),
+
+		HttpPanicsTotal: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace:   config.Namespace,
+			Subsystem:   config.Subsystem,
+			Name:        "panics_total",
+			Help:        "The total number of panics recovered in HTTP handlers.",
+			ConstLabels: constLabels,
+		}, []string{labelPath, labelMethod, labelKind}),
 	}
 
-	r.Registry = prometheus.DefaultRegisterer
+	r.Registry = config.Registerer
+	r.gatherer = config.Gatherer
 
-	r.Registry.MustRegister(
-		r.HttpRequestsTotal,
-		r.HttpRequestsDurationsHistogram,
-		r.HttpResponseSizeHistogram,
+	var inFlightMax prometheus.Collector = r.HttpRequestsInFlightMax
+
+	err := metrics.Register(r.Registry,
+		&r.HttpRequestsTotal,
+		metrics.Histogram(&r.HttpRequestsDurationsHistogram, config.DurationBuckets),
+		metrics.Histogram(&r.HttpResponseSizeHistogram, config.SizeBuckets),
+		metrics.Histogram(&r.HttpRequestSizeHistogram, config.SizeBuckets),
+		&r.HttpRequestsInFlight,
+		&inFlightMax,
+		&r.HttpPanicsTotal,
 	)
+	if err != nil {
+		return nil, err
+	}
 
-	return r
+	// Recorders created with the same configuration share the watermark too.
+	w, ok := inFlightMax.(*watermark)
+	if !ok {
+		return nil, fmt.Errorf("collector of type %T has been already registered instead of %T", inFlightMax, r.HttpRequestsInFlightMax)
+	}
+	r.HttpRequestsInFlightMax = w
+
+	return r, nil
 }
 
 // Collect updates metrics using passed properties
@@ -86,11 +167,34 @@ func (r recorder) Collect(props metrics.HTTPReqProperties, duration time.Duratio
 	r.HttpRequestsTotal.WithLabelValues(props.Path, props.Method, props.Code).Inc()
 	r.HttpRequestsDurationsHistogram.WithLabelValues(props.Path, props.Method, props.Code).Observe(duration.Seconds())
 	r.HttpResponseSizeHistogram.WithLabelValues(props.Path, props.Method, props.Code).Observe(float64(bytesWritten))
+	r.HttpRequestSizeHistogram.WithLabelValues(props.Path, props.Method, props.Code).Observe(float64(props.BytesRead))
+}
+
+// CollectInFlight changes the number of requests in flight by delta
+func (r recorder) CollectInFlight(props metrics.HTTPReqProperties, delta int) {
+	r.HttpRequestsInFlight.WithLabelValues(props.Path, props.Method).Add(float64(delta))
+	r.HttpRequestsInFlightMax.Add(delta)
+}
+
+// CollectPanic updates metrics of panics using passed properties
+func (r recorder) CollectPanic(props metrics.HTTPPanicProperties) {
+	r.HttpPanicsTotal.WithLabelValues(props.Path, props.Method, props.Kind).Inc()
+}
+
+// Gatherer returns the gatherer used for gathering recorded metrics.
+func (r recorder) Gatherer() prometheus.Gatherer {
+	return r.gatherer
 }
 
 // Unregister ...
 func (r recorder) Unregister() {
-	r.Registry.Unregister(r.HttpRequestsTotal)
-	r.Registry.Unregister(r.HttpRequestsDurationsHistogram)
-	r.Registry.Unregister(r.HttpResponseSizeHistogram)
+	metrics.Unregister(r.Registry,
+		r.HttpRequestsTotal,
+		r.HttpRequestsDurationsHistogram,
+		r.HttpResponseSizeHistogram,
+		r.HttpRequestSizeHistogram,
+		r.HttpRequestsInFlight,
+		r.HttpRequestsInFlightMax,
+		r.HttpPanicsTotal,
+	)
 }
@@ -4,6 +4,7 @@ import (
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 	"github.com/weaponry/go-instrumenting/metrics"
 	httpmetrics "github.com/weaponry/go-instrumenting/metrics/http"
 	"io/ioutil"
@@ -128,6 +129,24 @@ func TestNewHttpRecorder(t *testing.T) {
 				`app_http_requests_total{application="test-app",method="GET",path="/test",status="200"} 3`,
 			},
 		},
+		{
+			name: "Custom options should be used for naming metrics.",
+			config: httpmetrics.Config{
+				Options: metrics.Options{
+					Namespace:   "acme",
+					Subsystem:   "web",
+					ConstLabels: prometheus.Labels{"team": "core", "env": "prod"},
+				},
+			},
+			recordMetrics: func(r metrics.HttpRecorder) {
+				r.Collect(metrics.HTTPReqProperties{Path: "/test", Method: http.MethodGet, Code: "200"}, 4*time.Second, 1000)
+			},
+			expMetrics: []string{
+				`acme_web_request_duration_seconds_count{application="test-app",env="prod",method="GET",path="/test",status="200",team="core"} 1`,
+				`acme_web_response_size_bytes_sum{application="test-app",env="prod",method="GET",path="/test",status="200",team="core"} 1000`,
+				`acme_web_requests_total{application="test-app",env="prod",method="GET",path="/test",status="200",team="core"} 1`,
+			},
+		},
 	}
 
 	for _, tc := range testCases {
@@ -153,3 +172,48 @@ func TestNewHttpRecorder(t *testing.T) {
 		})
 	}
 }
+
+func TestRegisterHttpRecorder(t *testing.T) {
+	registry := prometheus.NewRegistry()
+
+	// Recorders with the same configuration should share metrics.
+	first, err := httpmetrics.RegisterHttpRecorder("test-app", httpmetrics.Config{Options: metrics.Options{Registerer: registry}})
+	require.NoError(t, err)
+	second, err := httpmetrics.RegisterHttpRecorder("test-app", httpmetrics.Config{Options: metrics.Options{Registerer: registry}})
+	require.NoError(t, err)
+
+	first.Collect(metrics.HTTPReqProperties{Path: "/test", Method: http.MethodGet, Code: "200"}, time.Second, 100)
+	second.Collect(metrics.HTTPReqProperties{Path: "/test", Method: http.MethodGet, Code: "200"}, time.Second, 100)
+
+	// Gatherer of the registry should be exposed by recorders.
+	provider, ok := first.(metrics.GathererProvider)
+	require.True(t, ok)
+	assert.Equal(t, registry, provider.Gatherer())
+
+	// Get the metrics handler and serve.
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest("GET", "/metrics", nil)
+	promhttp.HandlerFor(provider.Gatherer(), promhttp.HandlerOpts{}).ServeHTTP(rec, req)
+
+	body, _ := ioutil.ReadAll(rec.Result().Body)
+	assert.Contains(t, string(body), `app_http_requests_total{application="test-app",method="GET",path="/test",status="200"} 2`)
+
+	// Metrics with the same name but different labels should not be registered.
+	conflicting := prometheus.NewRegistry()
+	conflicting.MustRegister(prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "app", Subsystem: "http", Name: "response_size_bytes", Help: "Conflicting metric.",
+	}, []string{"path"}))
+
+	_, err = httpmetrics.RegisterHttpRecorder("test-app", httpmetrics.Config{Options: metrics.Options{Registerer: conflicting}})
+	assert.Error(t, err)
+	assert.Panics(t, func() {
+		httpmetrics.NewHttpRecorder("test-app", httpmetrics.Config{Options: metrics.Options{Registerer: conflicting}})
+	})
+
+	// Collectors registered before the failure should be unregistered.
+	families, err := conflicting.Gather()
+	require.NoError(t, err)
+	for _, family := range families {
+		assert.NotEqual(t, "app_http_requests_total", family.GetName())
+	}
+}
@@ -0,0 +1,47 @@
+package http
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"sync"
+)
+
+// watermark is a gauge exporting the maximum number of requests in flight since the previous scrape. Each scrape
+// resets the maximum to the current number of requests, hence it is accurate when metrics are scraped by a single
+// Prometheus server.
+type watermark struct {
+	desc *prometheus.Desc
+
+	mu      sync.Mutex
+	current int
+	max     int
+}
+
+func newWatermark(desc *prometheus.Desc) *watermark {
+	return &watermark{desc: desc}
+}
+
+// Add changes the current number of requests in flight by delta.
+func (w *watermark) Add(delta int) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.current += delta
+	if w.current > w.max {
+		w.max = w.current
+	}
+}
+
+// Describe implements prometheus.Collector.
+func (w *watermark) Describe(ch chan<- *prometheus.Desc) {
+	ch <- w.desc
+}
+
+// Collect implements prometheus.Collector.
+func (w *watermark) Collect(ch chan<- prometheus.Metric) {
+	w.mu.Lock()
+	max := w.max
+	w.max = w.current
+	w.mu.Unlock()
+
+	ch <- prometheus.MustNewConstMetric(w.desc, prometheus.GaugeValue, float64(max))
+}
@@ -0,0 +1,138 @@
+package http
+
+import (
+	"github.com/weaponry/go-instrumenting/metrics"
+	"io"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Option configures the HTTP middleware.
+type Option func(*options)
+
+type options struct {
+	resolver      PathResolver
+	repanic       bool
+	panicResponse func(w http.ResponseWriter, r *http.Request, p interface{})
+}
+
+func newOptions(opts []Option) *options {
+	o := &options{
+		resolver:      NewRuleResolver(),
+		panicResponse: defaultPanicResponse,
+	}
+
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	return o
+}
+
+// WithPathResolver sets the resolver used for building the path label of the request, by default URL path of
+// the request normalized with DefaultRules is used. Requests not matched by the resolver are recorded as '<unmatched>'.
+func WithPathResolver(resolver PathResolver) Option {
+	return func(o *options) {
+		o.resolver = resolver
+	}
+}
+
+// WithPathFunc sets the function used for building the path label of the request.
+func WithPathFunc(f func(r *http.Request) string) Option {
+	return WithPathResolver(PathResolverFunc(func(r *http.Request) (string, bool) {
+		return f(r), true
+	}))
+}
+
+// Middleware returns a middleware which measures every request passed through it and
+// collects request's properties, duration and sizes of the request and the response using recorder.
+// Requests are counted as in flight until the handler returns, panics or hijacks the connection, if recorder
+// implements metrics.HttpInFlightRecorder.
+// Panicking requests are recorded with 500 status, or 'aborted' if the handler panics with http.ErrAbortHandler.
+func Middleware(recorder metrics.HttpRecorder, opts ...Option) func(http.Handler) http.Handler {
+	o := newOptions(opts)
+	inFlightRecorder, _ := recorder.(metrics.HttpInFlightRecorder)
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			var inFlight metrics.HTTPReqProperties
+			if inFlightRecorder != nil {
+				inFlight = metrics.HTTPReqProperties{
+					Path:   resolvePath(o.resolver, r),
+					Method: r.Method,
+				}
+				inFlightRecorder.CollectInFlight(inFlight, 1)
+			}
+
+			var once sync.Once
+			done := func() {
+				once.Do(func() {
+					if inFlightRecorder != nil {
+						inFlightRecorder.CollectInFlight(inFlight, -1)
+					}
+				})
+			}
+
+			rw := newResponseWriter(w, done)
+
+			// Count bytes actually read by the handler, Content-Length is unknown for chunked requests.
+			body := &requestBody{ReadCloser: r.Body}
+			if r.Body != nil {
+				r.Body = body
+			}
+
+			start := time.Now()
+			defer func() {
+				done()
+
+				props := metrics.HTTPReqProperties{
+					Path:      resolvePath(o.resolver, r),
+					Method:    r.Method,
+					Code:      statusLabel(rw),
+					BytesRead: body.bytesRead,
+				}
+
+				// Panicking handler fails the request whatever status it has written, the panic is passed further.
+				p := recover()
+				tracker, tracked := rw.(recoveryTracker)
+				switch {
+				case rw.Hijacked():
+				case p != nil:
+					props.Code = panicStatusLabel(p)
+				case tracked && tracker.panicRecovered():
+					props.Code = panicStatusLabel(nil)
+				}
+
+				recorder.Collect(props, time.Since(start), rw.BytesWritten())
+
+				if p != nil {
+					panic(p)
+				}
+			}()
+
+			next.ServeHTTP(rw, r)
+		})
+	}
+}
+
+// requestBody counts bytes read from the request body.
+type requestBody struct {
+	io.ReadCloser
+	bytesRead int
+}
+
+func (b *requestBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	b.bytesRead += n
+	return n, err
+}
+
+// statusLabel returns value of the status label for the response written by rw.
+func statusLabel(rw ResponseWriter) string {
+	if rw.Hijacked() {
+		return statusHijacked
+	}
+	return strconv.Itoa(rw.Status())
+}
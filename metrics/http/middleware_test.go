@@ -0,0 +1,213 @@
+package http_test
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/weaponry/go-instrumenting/metrics"
+	httpmetrics "github.com/weaponry/go-instrumenting/metrics/http"
+	"github.com/weaponry/go-instrumenting/metrics/metricstest"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMiddleware(t *testing.T) {
+	testCases := []struct {
+		name       string
+		options    []httpmetrics.Option
+		handler    http.HandlerFunc
+		method     string
+		target     string
+		expMetrics []string
+	}{
+		{
+			name: "Handler without WriteHeader should be measured with 200 status.",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				_, _ = w.Write([]byte("hello"))
+			},
+			method: http.MethodGet,
+			target: "/test?foo=bar",
+			expMetrics: []string{
+				`app_http_requests_total{application="test-app",method="GET",path="/test",status="200"} 1`,
+				`app_http_response_size_bytes_sum{application="test-app",method="GET",path="/test",status="200"} 5`,
+			},
+		},
+		{
+			name: "Handler with explicit status should be measured with that status.",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusNotFound)
+				w.WriteHeader(http.StatusInternalServerError)
+				_, _ = w.Write([]byte("not found"))
+			},
+			method: http.MethodPost,
+			target: "/test",
+			expMetrics: []string{
+				`app_http_requests_total{application="test-app",method="POST",path="/test",status="404"} 1`,
+				`app_http_response_size_bytes_sum{application="test-app",method="POST",path="/test",status="404"} 9`,
+			},
+		},
+		{
+			name:    "Custom path function should be used for the path label.",
+			options: []httpmetrics.Option{httpmetrics.WithPathFunc(func(r *http.Request) string { return "/custom" })},
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusNoContent)
+			},
+			method: http.MethodDelete,
+			target: "/test/123",
+			expMetrics: []string{
+				`app_http_requests_total{application="test-app",method="DELETE",path="/custom",status="204"} 1`,
+				`app_http_response_size_bytes_sum{application="test-app",method="DELETE",path="/custom",status="204"} 0`,
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			metricRecorder := httpmetrics.NewHttpRecorder("test-app", httpmetrics.Config{})
+			handler := httpmetrics.Middleware(metricRecorder, tc.options...)(tc.handler)
+
+			handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(tc.method, tc.target, nil))
+
+			// Get the metrics handler and serve.
+			rec := httptest.NewRecorder()
+			req := httptest.NewRequest("GET", "/metrics", nil)
+			promhttp.HandlerFor(prometheus.DefaultGatherer, promhttp.HandlerOpts{}).ServeHTTP(rec, req)
+
+			resp := rec.Result()
+
+			// Check all metrics are present.
+			if assert.Equal(t, http.StatusOK, resp.StatusCode) {
+				body, _ := ioutil.ReadAll(resp.Body)
+				for _, expMetric := range tc.expMetrics {
+					assert.Contains(t, string(body), expMetric, "metric not present on the result")
+				}
+			}
+			metricRecorder.Unregister()
+		})
+	}
+}
+
+func TestMiddlewareInFlight(t *testing.T) {
+	registry := prometheus.NewRegistry()
+	recorder := httpmetrics.NewHttpRecorder("test-app", httpmetrics.Config{Options: metrics.Options{Registerer: registry}})
+
+	var (
+		started = make(chan struct{})
+		release = make(chan struct{})
+	)
+
+	h := httpmetrics.Middleware(recorder)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/panic":
+			panic("handler failed")
+		case "/hijack":
+			conn, _, err := w.(http.Hijacker).Hijack()
+			if err == nil {
+				_ = conn.Close()
+			}
+		}
+		started <- struct{}{}
+		<-release
+	}))
+	server := httptest.NewServer(h)
+	defer server.Close()
+
+	inFlight := prometheus.Labels{"path": "/slow", "method": "GET"}
+
+	// Two concurrent requests should be in flight.
+	for i := 0; i < 2; i++ {
+		go func() {
+			resp, err := http.Get(server.URL + "/slow")
+			if err == nil {
+				_ = resp.Body.Close()
+			}
+		}()
+		<-started
+	}
+	metricstest.AssertGauge(t, registry, "app_http_requests_in_flight", inFlight, 2)
+
+	release <- struct{}{}
+	release <- struct{}{}
+	assert.Eventually(t, func() bool {
+		return metricstest.GaugeValue(t, registry, "app_http_requests_in_flight", inFlight) == 0
+	}, time.Second, 10*time.Millisecond)
+
+	// The watermark should be reset by the scrape to the current number of requests.
+	inFlightRecorder, ok := recorder.(metrics.HttpInFlightRecorder)
+	require.True(t, ok)
+	inFlightRecorder.CollectInFlight(metrics.HTTPReqProperties{Path: "/slow", Method: "GET"}, 3)
+	inFlightRecorder.CollectInFlight(metrics.HTTPReqProperties{Path: "/slow", Method: "GET"}, -3)
+	metricstest.AssertGauge(t, registry, "app_http_requests_in_flight_max", nil, 3)
+	metricstest.AssertGauge(t, registry, "app_http_requests_in_flight_max", nil, 0)
+
+	// Panicking request should not be left in flight.
+	func() {
+		defer func() { assert.NotNil(t, recover()) }()
+		h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/panic", nil))
+	}()
+	metricstest.AssertGauge(t, registry, "app_http_requests_in_flight", prometheus.Labels{"path": "/panic"}, 0)
+
+	// Request should not be in flight after its connection has been hijacked.
+	go func() {
+		resp, err := http.Get(server.URL + "/hijack")
+		if err == nil {
+			_ = resp.Body.Close()
+		}
+	}()
+	<-started
+	metricstest.AssertGauge(t, registry, "app_http_requests_in_flight", prometheus.Labels{"path": "/hijack"}, 0)
+	release <- struct{}{}
+}
+
+func TestMiddlewareRequestSize(t *testing.T) {
+	registry := prometheus.NewRegistry()
+	recorder := httpmetrics.NewHttpRecorder("test-app", httpmetrics.Config{Options: metrics.Options{Registerer: registry}})
+
+	server := httptest.NewServer(httpmetrics.Middleware(recorder)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, []string{"chunked"}, r.TransferEncoding)
+		_, _ = io.Copy(ioutil.Discard, r.Body)
+	})))
+	defer server.Close()
+
+	// Body of unknown length is sent chunked.
+	body := struct{ io.Reader }{strings.NewReader(strings.Repeat("a", 1500))}
+	req, err := http.NewRequest("POST", server.URL+"/upload", body)
+	require.NoError(t, err)
+	req.ContentLength = -1
+
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	require.NoError(t, resp.Body.Close())
+
+	labels := prometheus.Labels{"path": "/upload", "method": "POST", "status": "200"}
+	metricstest.AssertHistogramCount(t, registry, "app_http_request_size_bytes", labels, 1)
+	assert.Equal(t, float64(1500), metricstest.HistogramSum(t, registry, "app_http_request_size_bytes", labels))
+}
+
+// baseHttpRecorder implements only metrics.HttpRecorder, as recorders created by other packages may do.
+type baseHttpRecorder struct {
+	calls []metrics.HTTPReqProperties
+}
+
+func (r *baseHttpRecorder) Collect(props metrics.HTTPReqProperties, _ time.Duration, _ int) {
+	r.calls = append(r.calls, props)
+}
+
+func (r *baseHttpRecorder) Unregister() {}
+
+func TestMiddlewareBaseRecorder(t *testing.T) {
+	recorder := &baseHttpRecorder{}
+	h := httpmetrics.Middleware(recorder)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusAccepted)
+	}))
+
+	// Requests should be recorded without counting them in flight.
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/users/1", nil))
+	assert.Equal(t, []metrics.HTTPReqProperties{{Path: "/users/:id", Method: "GET", Code: "202"}}, recorder.calls)
+}
@@ -0,0 +1,40 @@
+// Package muxresolver resolves paths of requests routed by gorilla/mux for HTTP metrics.
+package muxresolver
+
+import (
+	"github.com/gorilla/mux"
+	httpmetrics "github.com/weaponry/go-instrumenting/metrics/http"
+	"net/http"
+)
+
+type resolver struct {
+	router *mux.Router
+}
+
+// New returns a PathResolver which uses path templates of gorilla/mux routes as paths of requests. Route matched
+// by the router is used when the middleware is installed with router.Use(), otherwise the request is matched against
+// router, which could be nil if the middleware is always used inside the router.
+func New(router *mux.Router) httpmetrics.PathResolver {
+	return resolver{router: router}
+}
+
+func (g resolver) ResolvePath(r *http.Request) (string, bool) {
+	route := mux.CurrentRoute(r)
+	if route == nil && g.router != nil {
+		var match mux.RouteMatch
+		if g.router.Match(r, &match) && match.MatchErr == nil {
+			route = match.Route
+		}
+	}
+
+	if route == nil {
+		return "", false
+	}
+
+	template, err := route.GetPathTemplate()
+	if err != nil {
+		return "", false
+	}
+
+	return template, true
+}
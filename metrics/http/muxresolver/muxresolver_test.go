@@ -0,0 +1,66 @@
+package muxresolver_test
+
+import (
+	"github.com/gorilla/mux"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/assert"
+	"github.com/weaponry/go-instrumenting/metrics"
+	httpmetrics "github.com/weaponry/go-instrumenting/metrics/http"
+	"github.com/weaponry/go-instrumenting/metrics/http/muxresolver"
+	"github.com/weaponry/go-instrumenting/metrics/metricstest"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestResolvePath(t *testing.T) {
+	noop := func(w http.ResponseWriter, r *http.Request) {}
+
+	router := mux.NewRouter()
+	router.HandleFunc("/users/{id:[0-9]+}", noop).Methods(http.MethodGet)
+
+	testCases := []struct {
+		name    string
+		method  string
+		target  string
+		expPath string
+		expOk   bool
+	}{
+		{
+			name:   "Gorilla mux should resolve route template.",
+			method: http.MethodGet, target: "/users/12345",
+			expPath: "/users/{id:[0-9]+}", expOk: true,
+		},
+		{
+			name:   "Gorilla mux should not resolve route with mismatched method.",
+			method: http.MethodPost, target: "/users/12345",
+			expOk: false,
+		},
+	}
+
+	resolver := muxresolver.New(router)
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			path, ok := resolver.ResolvePath(httptest.NewRequest(tc.method, tc.target, nil))
+			assert.Equal(t, tc.expOk, ok)
+			if tc.expOk {
+				assert.Equal(t, tc.expPath, path)
+			}
+		})
+	}
+}
+
+func TestMiddlewarePathResolverInFlight(t *testing.T) {
+	registry := prometheus.NewRegistry()
+	metricRecorder := httpmetrics.NewHttpRecorder("test-app", httpmetrics.Config{Options: metrics.Options{Registerer: registry}})
+
+	router := mux.NewRouter()
+	router.Use(httpmetrics.Middleware(metricRecorder, httpmetrics.WithPathResolver(muxresolver.New(nil))))
+	router.HandleFunc("/users/{id}", func(w http.ResponseWriter, r *http.Request) {
+		metricstest.AssertGauge(t, registry, "app_http_requests_in_flight", prometheus.Labels{"path": "/users/{id}"}, 1)
+	})
+
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/users/1", nil))
+
+	metricstest.AssertCounter(t, registry, "app_http_requests_total", prometheus.Labels{"path": "/users/{id}", "status": "200"}, 1)
+}
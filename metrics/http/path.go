@@ -0,0 +1,116 @@
+package http
+
+import (
+	"net/http"
+	"regexp"
+	"strings"
+)
+
+// pathUnmatched is a value of the path label used for requests which don't match any known route.
+const pathUnmatched = "<unmatched>"
+
+// PathResolver knows how to turn the request into a normalized path used as a value of the path label.
+type PathResolver interface {
+	// ResolvePath returns normalized path of the request, or false if the request doesn't match any known route.
+	ResolvePath(r *http.Request) (string, bool)
+}
+
+// PathResolverFunc is an adapter to allow the use of ordinary functions as PathResolver.
+type PathResolverFunc func(r *http.Request) (string, bool)
+
+// ResolvePath calls f(r).
+func (f PathResolverFunc) ResolvePath(r *http.Request) (string, bool) {
+	return f(r)
+}
+
+// resolvePath returns value of the path label for the request.
+func resolvePath(resolver PathResolver, r *http.Request) string {
+	if path, ok := resolver.ResolvePath(r); ok {
+		return path
+	}
+	return pathUnmatched
+}
+
+/*
+ * net/http ServeMux
+ */
+
+type serveMuxResolver struct {
+	mux *http.ServeMux
+}
+
+// NewServeMuxResolver returns a PathResolver which uses patterns registered in mux as paths of requests.
+func NewServeMuxResolver(mux *http.ServeMux) PathResolver {
+	return serveMuxResolver{mux: mux}
+}
+
+func (s serveMuxResolver) ResolvePath(r *http.Request) (string, bool) {
+	_, pattern := s.mux.Handler(r)
+	if pattern == "" {
+		return "", false
+	}
+
+	// Patterns may be prefixed with method, e.g. 'GET /users/{id}', method is a label on its own.
+	if i := strings.IndexByte(pattern, ' '); i >= 0 {
+		pattern = strings.TrimLeft(pattern[i:], " ")
+	}
+
+	return pattern, true
+}
+
+/*
+ * Rule-based normalizer
+ */
+
+// Rule replaces path segments entirely matched by Pattern with Replacement.
+type Rule struct {
+	Pattern     *regexp.Regexp
+	Replacement string
+}
+
+// DefaultRules replace UUID, numeric and hexadecimal (at least 16 characters long) segments of the path with placeholders.
+var DefaultRules = []Rule{
+	{Pattern: regexp.MustCompile(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`), Replacement: ":uuid"},
+	{Pattern: regexp.MustCompile(`^[0-9]+$`), Replacement: ":id"},
+	{Pattern: regexp.MustCompile(`^[0-9a-fA-F]{16,}$`), Replacement: ":hex"},
+}
+
+// RuleResolver normalizes URL path of the request by replacing segments with placeholders according to rules.
+// It never fails to resolve the path, hence requests are never considered as unmatched.
+type RuleResolver struct {
+	rules []Rule
+}
+
+// NewRuleResolver creates RuleResolver with passed rules, DefaultRules are used if no rules passed.
+func NewRuleResolver(rules ...Rule) *RuleResolver {
+	if len(rules) == 0 {
+		rules = DefaultRules
+	}
+	return &RuleResolver{rules: rules}
+}
+
+func (n *RuleResolver) ResolvePath(r *http.Request) (string, bool) {
+	return n.Normalize(r.URL.Path), true
+}
+
+// Normalize strips query string from the path and replaces its segments according to the rules.
+func (n *RuleResolver) Normalize(path string) string {
+	if i := strings.IndexByte(path, '?'); i >= 0 {
+		path = path[:i]
+	}
+
+	segments := strings.Split(path, "/")
+	for i, segment := range segments {
+		if segment == "" {
+			continue
+		}
+		for _, rule := range n.rules {
+			if rule.Pattern.MatchString(segment) {
+				segments[i] = rule.Replacement
+				break
+			}
+		}
+	}
+
+	return strings.Join(segments, "/")
+}
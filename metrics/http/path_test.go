@@ -0,0 +1,65 @@
+package http_test
+
+import (
+	"github.com/stretchr/testify/assert"
+	httpmetrics "github.com/weaponry/go-instrumenting/metrics/http"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestPathResolvers(t *testing.T) {
+	noop := func(w http.ResponseWriter, r *http.Request) {}
+
+	serveMux := http.NewServeMux()
+	serveMux.HandleFunc("/users/", noop)
+	serveMux.HandleFunc("/health", noop)
+
+	testCases := []struct {
+		name     string
+		resolver httpmetrics.PathResolver
+		method   string
+		target   string
+		expPath  string
+		expOk    bool
+	}{
+		{
+			name:     "ServeMux should resolve registered pattern.",
+			resolver: httpmetrics.NewServeMuxResolver(serveMux),
+			method:   http.MethodGet, target: "/users/12345?expand=true",
+			expPath: "/users/", expOk: true,
+		},
+		{
+			name:     "ServeMux should not resolve unknown path.",
+			resolver: httpmetrics.NewServeMuxResolver(serveMux),
+			method:   http.MethodGet, target: "/unknown",
+			expOk: false,
+		},
+		{
+			name:     "Rule resolver should replace identifiers with placeholders.",
+			resolver: httpmetrics.NewRuleResolver(),
+			method:   http.MethodGet, target: "/users/12345/keys/0123456789abcdef0123/v1?x=1",
+			expPath: "/users/:id/keys/:hex/v1", expOk: true,
+		},
+		{
+			name:     "Rule resolver should replace UUID with placeholder.",
+			resolver: httpmetrics.NewRuleResolver(),
+			method:   http.MethodGet, target: "/orders/3f2b8a4e-9c1d-4f6a-8b7e-2d5c9a1e0f34/items/",
+			expPath: "/orders/:uuid/items/", expOk: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			path, ok := tc.resolver.ResolvePath(httptest.NewRequest(tc.method, tc.target, nil))
+			assert.Equal(t, tc.expOk, ok)
+			if tc.expOk {
+				assert.Equal(t, tc.expPath, path)
+			}
+		})
+	}
+}
+
+func TestRuleResolverNormalize(t *testing.T) {
+	assert.Equal(t, "/users/:id/posts", httpmetrics.NewRuleResolver().Normalize("/users/42/posts?page=2"))
+}
@@ -0,0 +1,99 @@
+package http
+
+import (
+	"github.com/weaponry/go-instrumenting/metrics"
+	"net/http"
+	"strconv"
+)
+
+const (
+	// Kinds of panics used as values of the kind label.
+	kindPanic = "panic"
+	kindAbort = "abort"
+)
+
+// recoveryTracker is implemented by the writer of Middleware, it is notified by Recoverer about recovered panics.
+type recoveryTracker interface {
+	setRecovered()
+	panicRecovered() bool
+}
+
+// WithRepanic makes the recovery middleware to panic again after the panic has been recorded, e.g. for passing it to
+// another recovery middleware. Ignored by Middleware.
+func WithRepanic() Option {
+	return func(o *options) {
+		o.repanic = true
+	}
+}
+
+// WithPanicResponse sets the function used for writing response of the request which handler has panicked with p, by
+// default plain text 500 Internal Server Error is written. The function is not called if the handler has already
+// written the status or hijacked the connection. Ignored by Middleware.
+func WithPanicResponse(f func(w http.ResponseWriter, r *http.Request, p interface{})) Option {
+	return func(o *options) {
+		o.panicResponse = f
+	}
+}
+
+// Recoverer returns a middleware which recovers panics of handlers, counts them using recorder (if it implements
+// metrics.HttpPanicRecorder) and responds with 500 Internal Server Error. Panics with http.ErrAbortHandler are counted
+// as aborts and always passed further, hence net/http aborts the response. Put Recoverer right inside Middleware for
+// recording recovered requests with 500 status regardless of the written response.
+func Recoverer(recorder metrics.HttpRecorder, opts ...Option) func(http.Handler) http.Handler {
+	o := newOptions(opts)
+	panicRecorder, _ := recorder.(metrics.HttpPanicRecorder)
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			rw := &responseWriter{ResponseWriter: w, code: http.StatusOK}
+
+			defer func() {
+				p := recover()
+				if p == nil {
+					return
+				}
+
+				kind := kindPanic
+				if p == http.ErrAbortHandler {
+					kind = kindAbort
+				}
+
+				if panicRecorder != nil {
+					props := metrics.HTTPPanicProperties{
+						Path:   resolvePath(o.resolver, r),
+						Method: r.Method,
+						Kind:   kind,
+					}
+					panicRecorder.CollectPanic(props)
+				}
+
+				if t, ok := w.(recoveryTracker); ok {
+					t.setRecovered()
+				}
+
+				if o.repanic || kind == kindAbort {
+					panic(p)
+				}
+
+				if !rw.wroteHeader && !rw.hijacked {
+					o.panicResponse(rw, r, p)
+				}
+			}()
+
+			next.ServeHTTP(wrapResponseWriter(rw), r)
+		})
+	}
+}
+
+// defaultPanicResponse writes plain text 500 Internal Server Error.
+func defaultPanicResponse(w http.ResponseWriter, _ *http.Request, _ interface{}) {
+	http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+}
+
+// panicStatusLabel returns value of the status label for the request which handler has panicked with p.
+func panicStatusLabel(p interface{}) string {
+	if p == http.ErrAbortHandler {
+		return statusAborted
+	}
+	return strconv.Itoa(http.StatusInternalServerError)
+}
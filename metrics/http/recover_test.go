@@ -0,0 +1,148 @@
+package http_test
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/weaponry/go-instrumenting/metrics"
+	httpmetrics "github.com/weaponry/go-instrumenting/metrics/http"
+	"github.com/weaponry/go-instrumenting/metrics/metricstest"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestRecoverer(t *testing.T) {
+	testCases := []struct {
+		name      string
+		options   []httpmetrics.Option
+		handler   http.HandlerFunc
+		expPanic  bool
+		expKind   string
+		expStatus string
+		expCode   int
+		expBody   string
+	}{
+		{
+			name: "Panic should be recovered and recorded with 500 status.",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				panic("handler failed")
+			},
+			expKind:   "panic",
+			expStatus: "500",
+			expCode:   http.StatusInternalServerError,
+			expBody:   "Internal Server Error\n",
+		},
+		{
+			name: "Panic after written status should not overwrite the response.",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusAccepted)
+				panic("handler failed")
+			},
+			expKind:   "panic",
+			expStatus: "500",
+			expCode:   http.StatusAccepted,
+		},
+		{
+			name: "Custom panic response should be written.",
+			options: []httpmetrics.Option{httpmetrics.WithPanicResponse(func(w http.ResponseWriter, r *http.Request, p interface{}) {
+				w.WriteHeader(http.StatusServiceUnavailable)
+				_, _ = w.Write([]byte(p.(string)))
+			})},
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				panic("try later")
+			},
+			expKind:   "panic",
+			expStatus: "500",
+			expCode:   http.StatusServiceUnavailable,
+			expBody:   "try later",
+		},
+		{
+			name:    "Panic should be passed further if repanic is requested.",
+			options: []httpmetrics.Option{httpmetrics.WithRepanic()},
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				panic("handler failed")
+			},
+			expPanic:  true,
+			expKind:   "panic",
+			expStatus: "500",
+		},
+		{
+			name: "Abort should be counted separately and passed further.",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				panic(http.ErrAbortHandler)
+			},
+			expPanic:  true,
+			expKind:   "abort",
+			expStatus: "aborted",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			registry := prometheus.NewRegistry()
+			recorder := httpmetrics.NewHttpRecorder("test-app", httpmetrics.Config{Options: metrics.Options{Registerer: registry}})
+
+			h := httpmetrics.Middleware(recorder)(httpmetrics.Recoverer(recorder, tc.options...)(tc.handler))
+
+			rec := httptest.NewRecorder()
+			func() {
+				defer func() {
+					p := recover()
+					assert.Equal(t, tc.expPanic, p != nil, "unexpected panic %v", p)
+				}()
+				h.ServeHTTP(rec, httptest.NewRequest("GET", "/users/1", nil))
+			}()
+
+			metricstest.AssertCounter(t, registry, "app_http_panics_total", prometheus.Labels{
+				"path":   "/users/:id",
+				"method": "GET",
+				"kind":   tc.expKind,
+			}, 1)
+			metricstest.AssertCounter(t, registry, "app_http_requests_total", prometheus.Labels{
+				"path":   "/users/:id",
+				"method": "GET",
+				"status": tc.expStatus,
+			}, 1)
+
+			if !tc.expPanic {
+				assert.Equal(t, tc.expCode, rec.Code)
+				assert.Equal(t, tc.expBody, rec.Body.String())
+			}
+		})
+	}
+}
+
+func TestMiddlewarePanic(t *testing.T) {
+	recorder := metricstest.NewHttpRecorder()
+
+	h := httpmetrics.Middleware(recorder)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("partial"))
+		panic("handler failed")
+	}))
+
+	func() {
+		defer func() {
+			assert.Equal(t, "handler failed", recover(), "panic should be passed further")
+		}()
+		h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil))
+	}()
+
+	calls := recorder.Calls()
+	require.Len(t, calls, 1)
+	assert.Equal(t, metrics.HTTPReqProperties{Path: "/", Method: "GET", Code: "500"}, calls[0].Props)
+	assert.Empty(t, recorder.Panics(), "panics should be counted by Recoverer only")
+}
+
+func TestRecovererBaseRecorder(t *testing.T) {
+	recorder := &baseHttpRecorder{}
+	h := httpmetrics.Middleware(recorder)(httpmetrics.Recoverer(recorder)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		panic("handler failed")
+	})))
+
+	// Panics should be recovered without counting them.
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest("GET", "/users/1", nil))
+	assert.Equal(t, http.StatusInternalServerError, rec.Code)
+	assert.Equal(t, []metrics.HTTPReqProperties{{Path: "/users/:id", Method: "GET", Code: "500"}}, recorder.calls)
+}
@@ -0,0 +1,239 @@
+package http
+
+import (
+	"bufio"
+	"io"
+	"net"
+	"net/http"
+)
+
+// ResponseWriter is a http.ResponseWriter which keeps track of the status code and
+// the number of bytes written into the response.
+type ResponseWriter interface {
+	http.ResponseWriter
+	// Status returns the status code of the response, 200 if status has not been written explicitly.
+	Status() int
+	// BytesWritten returns the number of bytes written into the response body.
+	BytesWritten() int
+	// Hijacked reports whether the underlying connection has been hijacked.
+	Hijacked() bool
+	// Unwrap returns the original http.ResponseWriter.
+	Unwrap() http.ResponseWriter
+}
+
+// NewResponseWriter wraps w into ResponseWriter. Returned writer implements exactly those of http.Flusher,
+// http.Hijacker, http.Pusher and io.ReaderFrom interfaces which are implemented by w.
+func NewResponseWriter(w http.ResponseWriter) ResponseWriter {
+	return newResponseWriter(w, nil)
+}
+
+// newResponseWriter wraps w into ResponseWriter, onHijack is called when connection is hijacked if it is not nil.
+func newResponseWriter(w http.ResponseWriter, onHijack func()) ResponseWriter {
+	return wrapResponseWriter(&responseWriter{ResponseWriter: w, code: http.StatusOK, onHijack: onHijack})
+}
+
+// wrapResponseWriter returns rw extended with those of optional interfaces which are implemented by the original writer.
+func wrapResponseWriter(rw *responseWriter) ResponseWriter {
+	w := rw.ResponseWriter
+
+	_, f := w.(http.Flusher)
+	_, h := w.(http.Hijacker)
+	_, p := w.(http.Pusher)
+	_, rf := w.(io.ReaderFrom)
+
+	switch {
+	case f && h && p && rf:
+		return struct {
+			*responseWriter
+			flusher
+			hijacker
+			pusher
+			readerFrom
+		}{rw, flusher{rw}, hijacker{rw}, pusher{rw}, readerFrom{rw}}
+	case f && h && p && !rf:
+		return struct {
+			*responseWriter
+			flusher
+			hijacker
+			pusher
+		}{rw, flusher{rw}, hijacker{rw}, pusher{rw}}
+	case f && h && !p && rf:
+		return struct {
+			*responseWriter
+			flusher
+			hijacker
+			readerFrom
+		}{rw, flusher{rw}, hijacker{rw}, readerFrom{rw}}
+	case f && h && !p && !rf:
+		return struct {
+			*responseWriter
+			flusher
+			hijacker
+		}{rw, flusher{rw}, hijacker{rw}}
+	case f && !h && p && rf:
+		return struct {
+			*responseWriter
+			flusher
+			pusher
+			readerFrom
+		}{rw, flusher{rw}, pusher{rw}, readerFrom{rw}}
+	case f && !h && p && !rf:
+		return struct {
+			*responseWriter
+			flusher
+			pusher
+		}{rw, flusher{rw}, pusher{rw}}
+	case f && !h && !p && rf:
+		return struct {
+			*responseWriter
+			flusher
+			readerFrom
+		}{rw, flusher{rw}, readerFrom{rw}}
+	case f && !h && !p && !rf:
+		return struct {
+			*responseWriter
+			flusher
+		}{rw, flusher{rw}}
+	case !f && h && p && rf:
+		return struct {
+			*responseWriter
+			hijacker
+			pusher
+			readerFrom
+		}{rw, hijacker{rw}, pusher{rw}, readerFrom{rw}}
+	case !f && h && p && !rf:
+		return struct {
+			*responseWriter
+			hijacker
+			pusher
+		}{rw, hijacker{rw}, pusher{rw}}
+	case !f && h && !p && rf:
+		return struct {
+			*responseWriter
+			hijacker
+			readerFrom
+		}{rw, hijacker{rw}, readerFrom{rw}}
+	case !f && h && !p && !rf:
+		return struct {
+			*responseWriter
+			hijacker
+		}{rw, hijacker{rw}}
+	case !f && !h && p && rf:
+		return struct {
+			*responseWriter
+			pusher
+			readerFrom
+		}{rw, pusher{rw}, readerFrom{rw}}
+	case !f && !h && p && !rf:
+		return struct {
+			*responseWriter
+			pusher
+		}{rw, pusher{rw}}
+	case !f && !h && !p && rf:
+		return struct {
+			*responseWriter
+			readerFrom
+		}{rw, readerFrom{rw}}
+	default:
+		return rw
+	}
+}
+
+// responseWriter intercepts status code and number of bytes written by the handler.
+type responseWriter struct {
+	http.ResponseWriter
+	code         int
+	bytesWritten int
+	wroteHeader  bool
+	hijacked     bool
+	onHijack     func()
+	recovered    bool
+}
+
+func (w *responseWriter) Status() int {
+	return w.code
+}
+
+func (w *responseWriter) BytesWritten() int {
+	return w.bytesWritten
+}
+
+func (w *responseWriter) Hijacked() bool {
+	return w.hijacked
+}
+
+func (w *responseWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+func (w *responseWriter) setRecovered() {
+	w.recovered = true
+}
+
+func (w *responseWriter) panicRecovered() bool {
+	return w.recovered
+}
+
+func (w *responseWriter) WriteHeader(statusCode int) {
+	// Informational responses are followed by the final one, so keep waiting for it.
+	if !w.wroteHeader && statusCode >= http.StatusOK {
+		w.code = statusCode
+		w.wroteHeader = true
+	}
+	w.ResponseWriter.WriteHeader(statusCode)
+}
+
+func (w *responseWriter) Write(p []byte) (int, error) {
+	// Writing body without explicit WriteHeader implies 200 OK.
+	w.wroteHeader = true
+
+	n, err := w.ResponseWriter.Write(p)
+	w.bytesWritten += n
+	return n, err
+}
+
+type flusher struct {
+	rw *responseWriter
+}
+
+func (f flusher) Flush() {
+	// Flushing without explicit WriteHeader implies 200 OK.
+	f.rw.wroteHeader = true
+	f.rw.ResponseWriter.(http.Flusher).Flush()
+}
+
+type hijacker struct {
+	rw *responseWriter
+}
+
+func (h hijacker) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	conn, brw, err := h.rw.ResponseWriter.(http.Hijacker).Hijack()
+	if err == nil {
+		h.rw.hijacked = true
+		if h.rw.onHijack != nil {
+			h.rw.onHijack()
+		}
+	}
+	return conn, brw, err
+}
+
+type pusher struct {
+	rw *responseWriter
+}
+
+func (p pusher) Push(target string, opts *http.PushOptions) error {
+	return p.rw.ResponseWriter.(http.Pusher).Push(target, opts)
+}
+
+type readerFrom struct {
+	rw *responseWriter
+}
+
+func (rf readerFrom) ReadFrom(src io.Reader) (int64, error) {
+	// Writing body without explicit WriteHeader implies 200 OK.
+	rf.rw.wroteHeader = true
+
+	n, err := rf.rw.ResponseWriter.(io.ReaderFrom).ReadFrom(src)
+	rf.rw.bytesWritten += int(n)
+	return n, err
+}
@@ -0,0 +1,152 @@
+package http_test
+
+import (
+	"bufio"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/stretchr/testify/assert"
+	httpmetrics "github.com/weaponry/go-instrumenting/metrics/http"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// plainWriter implements only http.ResponseWriter.
+type plainWriter struct {
+	header http.Header
+	body   strings.Builder
+	code   int
+}
+
+func (w *plainWriter) Header() http.Header         { return w.header }
+func (w *plainWriter) Write(p []byte) (int, error) { return w.body.Write(p) }
+func (w *plainWriter) WriteHeader(code int)        { w.code = code }
+
+// hijackWriter additionally implements http.Hijacker.
+type hijackWriter struct {
+	plainWriter
+}
+
+func (w *hijackWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) { return nil, nil, nil }
+
+// fullWriter implements all optional interfaces.
+type fullWriter struct {
+	plainWriter
+	flushed bool
+}
+
+func (w *fullWriter) Flush()                                       { w.flushed = true }
+func (w *fullWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) { return nil, nil, nil }
+func (w *fullWriter) Push(string, *http.PushOptions) error         { return nil }
+func (w *fullWriter) ReadFrom(r io.Reader) (int64, error)          { return io.Copy(&w.body, r) }
+
+func TestNewResponseWriter(t *testing.T) {
+	testCases := []struct {
+		name        string
+		writer      http.ResponseWriter
+		expFlusher  bool
+		expHijacker bool
+		expPusher   bool
+		expReadFrom bool
+	}{
+		{
+			name:   "Plain writer should not get any optional interfaces.",
+			writer: &plainWriter{header: http.Header{}},
+		},
+		{
+			name:        "Hijacker should be preserved alone.",
+			writer:      &hijackWriter{plainWriter{header: http.Header{}}},
+			expHijacker: true,
+		},
+		{
+			name:       "Recorder should preserve only flusher.",
+			writer:     httptest.NewRecorder(),
+			expFlusher: true,
+		},
+		{
+			name:        "All optional interfaces should be preserved.",
+			writer:      &fullWriter{plainWriter: plainWriter{header: http.Header{}}},
+			expFlusher:  true,
+			expHijacker: true,
+			expPusher:   true,
+			expReadFrom: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			rw := httpmetrics.NewResponseWriter(tc.writer)
+
+			_, ok := rw.(http.Flusher)
+			assert.Equal(t, tc.expFlusher, ok, "http.Flusher")
+			_, ok = rw.(http.Hijacker)
+			assert.Equal(t, tc.expHijacker, ok, "http.Hijacker")
+			_, ok = rw.(http.Pusher)
+			assert.Equal(t, tc.expPusher, ok, "http.Pusher")
+			_, ok = rw.(io.ReaderFrom)
+			assert.Equal(t, tc.expReadFrom, ok, "io.ReaderFrom")
+
+			assert.Equal(t, tc.writer, rw.Unwrap())
+		})
+	}
+}
+
+func TestResponseWriterCounting(t *testing.T) {
+	w := &fullWriter{plainWriter: plainWriter{header: http.Header{}}}
+	rw := httpmetrics.NewResponseWriter(w)
+
+	rw.WriteHeader(http.StatusContinue)
+	assert.Equal(t, http.StatusOK, rw.Status(), "informational status should not be recorded")
+
+	rw.WriteHeader(http.StatusAccepted)
+	_, _ = rw.Write([]byte("hello"))
+	_, _ = rw.(io.ReaderFrom).ReadFrom(strings.NewReader(", world"))
+	rw.(http.Flusher).Flush()
+
+	assert.Equal(t, http.StatusAccepted, rw.Status())
+	assert.Equal(t, 12, rw.BytesWritten())
+	assert.Equal(t, "hello, world", w.body.String())
+	assert.True(t, w.flushed)
+	assert.False(t, rw.Hijacked())
+}
+
+func TestMiddlewareHijacked(t *testing.T) {
+	metricRecorder := httpmetrics.NewHttpRecorder("test-app", httpmetrics.Config{})
+	defer metricRecorder.Unregister()
+
+	handler := httpmetrics.Middleware(metricRecorder)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, brw, err := w.(http.Hijacker).Hijack()
+		if err != nil {
+			return
+		}
+		_, _ = brw.WriteString("HTTP/1.1 101 Switching Protocols\r\nConnection: close\r\n\r\n")
+		_ = brw.Flush()
+		_ = conn.Close()
+	}))
+
+	// Metrics are collected after response has been sent, so wait until the middleware returns.
+	done := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		defer close(done)
+		handler.ServeHTTP(w, r)
+	}))
+	defer srv.Close()
+
+	resp, err := http.Get(srv.URL + "/ws")
+	if assert.NoError(t, err) {
+		_ = resp.Body.Close()
+	}
+	<-done
+
+	// Get the metrics handler and serve.
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest("GET", "/metrics", nil)
+	promhttp.HandlerFor(prometheus.DefaultGatherer, promhttp.HandlerOpts{}).ServeHTTP(rec, req)
+
+	body, _ := ioutil.ReadAll(rec.Result().Body)
+	assert.Contains(t, string(body), `app_http_requests_total{application="test-app",method="GET",path="/ws",status="hijacked"} 1`)
+}
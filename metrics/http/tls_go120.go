@@ -0,0 +1,16 @@
+//go:build go1.20
+// +build go1.20
+
+package http
+
+import (
+	"crypto/tls"
+	"errors"
+)
+
+// isCertificateVerificationError reports whether err is *tls.CertificateVerificationError, which is returned by
+// handshakes since Go 1.20.
+func isCertificateVerificationError(err error) bool {
+	var verificationErr *tls.CertificateVerificationError
+	return errors.As(err, &verificationErr)
+}
@@ -0,0 +1,10 @@
+//go:build !go1.20
+// +build !go1.20
+
+package http
+
+// isCertificateVerificationError always returns false, errors of certificate verification are returned as they are
+// before Go 1.20.
+func isCertificateVerificationError(err error) bool {
+	return false
+}
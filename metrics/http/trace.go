@@ -0,0 +1,119 @@
+package http
+
+import (
+	"crypto/tls"
+	"github.com/weaponry/go-instrumenting/metrics"
+	"net/http/httptrace"
+	"sync"
+	"time"
+)
+
+const (
+	// Phases of outbound requests used as values of the phase label.
+	phaseDNS       = "dns"
+	phaseConnect   = "connect"
+	phaseTLS       = "tls"
+	phaseFirstByte = "first_byte"
+	phaseTransfer  = "transfer"
+
+	connectionNew    = "new"
+	connectionReused = "reused"
+)
+
+// tracer records durations of phases of outbound request reported by httptrace. Hooks of the trace could be called
+// from different goroutines, even after the request has been finished.
+type tracer struct {
+	recorder metrics.HttpClientRecorder
+	host     string
+
+	mu           sync.Mutex
+	dnsStart     time.Time
+	connectStart time.Time
+	tlsStart     time.Time
+	wroteRequest time.Time
+	firstByte    time.Time
+}
+
+func newTracer(recorder metrics.HttpClientRecorder, host string) *tracer {
+	return &tracer{recorder: recorder, host: host}
+}
+
+// clientTrace returns trace with hooks of the tracer.
+func (t *tracer) clientTrace() *httptrace.ClientTrace {
+	return &httptrace.ClientTrace{
+		DNSStart: func(httptrace.DNSStartInfo) {
+			t.start(&t.dnsStart)
+		},
+		DNSDone: func(info httptrace.DNSDoneInfo) {
+			t.done(&t.dnsStart, phaseDNS, info.Err)
+		},
+		ConnectStart: func(_, _ string) {
+			t.start(&t.connectStart)
+		},
+		ConnectDone: func(_, _ string, err error) {
+			t.done(&t.connectStart, phaseConnect, err)
+		},
+		TLSHandshakeStart: func() {
+			t.start(&t.tlsStart)
+		},
+		TLSHandshakeDone: func(_ tls.ConnectionState, err error) {
+			t.done(&t.tlsStart, phaseTLS, err)
+		},
+		GotConn: func(info httptrace.GotConnInfo) {
+			connection := connectionNew
+			if info.Reused {
+				connection = connectionReused
+			}
+			t.recorder.CollectConnection(metrics.HTTPClientConnProperties{Host: t.host, Connection: connection})
+		},
+		WroteRequest: func(httptrace.WroteRequestInfo) {
+			t.start(&t.wroteRequest)
+		},
+		GotFirstResponseByte: func() {
+			t.start(&t.firstByte)
+			t.between(&t.wroteRequest, &t.firstByte, phaseFirstByte)
+		},
+	}
+}
+
+// transferDone records duration of reading the response body.
+func (t *tracer) transferDone() {
+	now := time.Now()
+	t.between(&t.firstByte, &now, phaseTransfer)
+}
+
+// start remembers the start time of a phase, only the first start is taken into account when the phase is repeated,
+// e.g. connections to several addresses are dialed in parallel.
+func (t *tracer) start(at *time.Time) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if at.IsZero() {
+		*at = time.Now()
+	}
+}
+
+// done records duration of the phase if it has succeeded, the phase is recorded once.
+func (t *tracer) done(start *time.Time, phase string, err error) {
+	t.mu.Lock()
+	from := *start
+	if err == nil {
+		*start = time.Time{}
+	}
+	t.mu.Unlock()
+
+	if err == nil && !from.IsZero() {
+		t.recorder.CollectPhase(metrics.HTTPClientPhaseProperties{Host: t.host, Phase: phase}, time.Since(from))
+	}
+}
+
+// between records duration of the phase between passed moments if both of them are known.
+func (t *tracer) between(from, to *time.Time, phase string) {
+	t.mu.Lock()
+	start, end := *from, *to
+	t.mu.Unlock()
+
+	if !start.IsZero() && !end.IsZero() {
+		t.recorder.CollectPhase(metrics.HTTPClientPhaseProperties{Host: t.host, Phase: phase}, end.Sub(start))
+	}
+}
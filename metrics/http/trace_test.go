@@ -0,0 +1,80 @@
+package http_test
+
+import (
+	"context"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/weaponry/go-instrumenting/metrics"
+	httpmetrics "github.com/weaponry/go-instrumenting/metrics/http"
+	"github.com/weaponry/go-instrumenting/metrics/metricstest"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/http/httptrace"
+	"strings"
+	"testing"
+)
+
+func TestTransportPhases(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("hello"))
+	}))
+	defer server.Close()
+
+	// Use host name for resolving it with DNS, certificate of the server is issued for example.com.
+	serverURL := strings.Replace(server.URL, "127.0.0.1", "localhost", 1)
+	host := strings.TrimPrefix(serverURL, "https://")
+
+	next := server.Client().Transport.(*http.Transport).Clone()
+	next.TLSClientConfig.ServerName = "example.com"
+
+	registry := prometheus.NewRegistry()
+	recorder := httpmetrics.NewHttpClientRecorder("test-app", httpmetrics.Config{Options: metrics.Options{Registerer: registry}})
+	client := &http.Client{Transport: httpmetrics.NewTransport(next, recorder)}
+
+	for i := 0; i < 2; i++ {
+		resp, err := client.Get(serverURL)
+		require.NoError(t, err)
+		_, err = ioutil.ReadAll(resp.Body)
+		require.NoError(t, err)
+		require.NoError(t, resp.Body.Close())
+	}
+
+	// The connection should be established once and reused by the second request.
+	for _, phase := range []string{"dns", "connect", "tls"} {
+		metricstest.AssertHistogramCount(t, registry, "app_http_client_phase_duration_seconds", prometheus.Labels{"host": host, "phase": phase}, 1)
+	}
+	for _, phase := range []string{"first_byte", "transfer"} {
+		metricstest.AssertHistogramCount(t, registry, "app_http_client_phase_duration_seconds", prometheus.Labels{"host": host, "phase": phase}, 2)
+	}
+
+	metricstest.AssertCounter(t, registry, "app_http_client_connections_total", prometheus.Labels{"host": host, "connection": "new"}, 1)
+	metricstest.AssertCounter(t, registry, "app_http_client_connections_total", prometheus.Labels{"host": host, "connection": "reused"}, 1)
+}
+
+func TestTransportKeepsClientTrace(t *testing.T) {
+	server := httptest.NewServer(http.NotFoundHandler())
+	defer server.Close()
+
+	recorder := metricstest.NewHttpClientRecorder()
+	client := &http.Client{Transport: httpmetrics.NewTransport(&http.Transport{}, recorder)}
+
+	var gotConn bool
+	ctx := httptrace.WithClientTrace(context.Background(), &httptrace.ClientTrace{
+		GotConn: func(httptrace.GotConnInfo) { gotConn = true },
+	})
+
+	req, err := http.NewRequest("GET", server.URL, nil)
+	require.NoError(t, err)
+
+	resp, err := client.Do(req.WithContext(ctx))
+	require.NoError(t, err)
+	require.NoError(t, resp.Body.Close())
+
+	assert.True(t, gotConn, "trace of the caller should be called")
+
+	connections := recorder.ConnectionCalls()
+	require.Len(t, connections, 1)
+	assert.Equal(t, "new", connections[0].Connection)
+}
@@ -0,0 +1,190 @@
+package http
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"github.com/weaponry/go-instrumenting/metrics"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptrace"
+	"strconv"
+	"sync"
+	"syscall"
+	"time"
+)
+
+const (
+	// valueNone is a value of operation, status and error labels used when there is nothing to report.
+	valueNone = "none"
+
+	// Classes of transport errors used as values of the error label.
+	errorCanceled          = "canceled"
+	errorTimeout           = "timeout"
+	errorDNS               = "dns"
+	errorConnectionRefused = "connection_refused"
+	errorConnectionReset   = "connection_reset"
+	errorTLS               = "tls"
+	errorOther             = "other"
+
+	keyOperation key = iota
+)
+
+type key int
+
+// WithOperation returns a copy of ctx carrying name of the operation, which is used as an operation label of
+// outbound requests sent with the context.
+func WithOperation(ctx context.Context, name string) context.Context {
+	return context.WithValue(ctx, keyOperation, name)
+}
+
+// operationName returns name of the operation passed via context.
+func operationName(ctx context.Context) string {
+	if name, ok := ctx.Value(keyOperation).(string); ok && name != "" {
+		return name
+	}
+	return valueNone
+}
+
+// transport records metrics of requests sent by wrapped round tripper.
+type transport struct {
+	next     http.RoundTripper
+	recorder metrics.HttpClientRecorder
+}
+
+// NewTransport returns http.RoundTripper which records metrics of requests sent with next, http.DefaultTransport is
+// used if next is nil. Metrics are recorded when response body is read till the end or closed, hence latency includes
+// reading of the body, or when next fails. Durations of request phases and reuse of connections are traced using
+// httptrace, hooks of the trace already attached to the request context are kept.
+func NewTransport(next http.RoundTripper, recorder metrics.HttpClientRecorder) http.RoundTripper {
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	return &transport{next: next, recorder: recorder}
+}
+
+func (t *transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	start := time.Now()
+
+	props := metrics.HTTPClientReqProperties{
+		Host:      req.URL.Host,
+		Operation: operationName(req.Context()),
+		Method:    req.Method,
+		Code:      valueNone,
+		Error:     valueNone,
+	}
+
+	tr := newTracer(t.recorder, props.Host)
+	req = req.WithContext(httptrace.WithClientTrace(req.Context(), tr.clientTrace()))
+
+	resp, err := t.next.RoundTrip(req)
+	if err != nil {
+		props.Error = classifyError(err)
+		t.recorder.Collect(props, time.Since(start), 0)
+		return resp, err
+	}
+
+	props.Code = strconv.Itoa(resp.StatusCode)
+
+	if resp.Body == nil {
+		t.recorder.Collect(props, time.Since(start), 0)
+		return resp, nil
+	}
+
+	b := &body{ReadCloser: resp.Body, recorder: t.recorder, tracer: tr, props: props, start: start}
+
+	// Body of 101 Switching Protocols response is writable.
+	if w, ok := resp.Body.(io.Writer); ok {
+		resp.Body = struct {
+			*body
+			io.Writer
+		}{b, w}
+	} else {
+		resp.Body = b
+	}
+
+	return resp, nil
+}
+
+// body records metrics of the request when response body is read till the end or closed.
+type body struct {
+	io.ReadCloser
+	recorder  metrics.HttpClientRecorder
+	tracer    *tracer
+	props     metrics.HTTPClientReqProperties
+	start     time.Time
+	bytesRead int
+	once      sync.Once
+}
+
+func (b *body) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	b.bytesRead += n
+
+	switch {
+	case err == io.EOF:
+		b.finish(nil)
+	case err != nil:
+		b.finish(err)
+	}
+
+	return n, err
+}
+
+func (b *body) Close() error {
+	err := b.ReadCloser.Close()
+	b.finish(nil)
+	return err
+}
+
+func (b *body) finish(err error) {
+	b.once.Do(func() {
+		b.tracer.transferDone()
+
+		props := b.props
+		if err != nil {
+			props.Error = classifyError(err)
+		}
+		b.recorder.Collect(props, time.Since(b.start), b.bytesRead)
+	})
+}
+
+// classifyError returns class of the transport error.
+func classifyError(err error) string {
+	var (
+		dnsErr *net.DNSError
+		netErr net.Error
+	)
+
+	switch {
+	case errors.Is(err, context.Canceled):
+		return errorCanceled
+	case errors.As(err, &dnsErr):
+		return errorDNS
+	case errors.Is(err, context.DeadlineExceeded), errors.As(err, &netErr) && netErr.Timeout():
+		return errorTimeout
+	case errors.Is(err, syscall.ECONNREFUSED):
+		return errorConnectionRefused
+	case errors.Is(err, syscall.ECONNRESET):
+		return errorConnectionReset
+	case isTLSError(err):
+		return errorTLS
+	default:
+		return errorOther
+	}
+}
+
+// isTLSError reports whether err is caused by malformed TLS records or verification of the server certificate.
+func isTLSError(err error) bool {
+	var (
+		recordErr      tls.RecordHeaderError
+		authorityErr   x509.UnknownAuthorityError
+		hostnameErr    x509.HostnameError
+		certificateErr x509.CertificateInvalidError
+	)
+
+	return errors.As(err, &recordErr) || errors.As(err, &authorityErr) || errors.As(err, &hostnameErr) ||
+		errors.As(err, &certificateErr) || isCertificateVerificationError(err)
+}
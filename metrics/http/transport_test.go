@@ -0,0 +1,263 @@
+package http_test
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/weaponry/go-instrumenting/metrics"
+	httpmetrics "github.com/weaponry/go-instrumenting/metrics/http"
+	"github.com/weaponry/go-instrumenting/metrics/metricstest"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+)
+
+type roundTripperFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripperFunc) RoundTrip(r *http.Request) (*http.Response, error) {
+	return f(r)
+}
+
+func TestTransport(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusAccepted)
+		_, _ = w.Write([]byte("hello"))
+	}))
+	defer server.Close()
+
+	recorder := metricstest.NewHttpClientRecorder()
+	client := &http.Client{Transport: httpmetrics.NewTransport(nil, recorder)}
+
+	req, err := http.NewRequest("POST", server.URL+"/orders", nil)
+	require.NoError(t, err)
+	req = req.WithContext(httpmetrics.WithOperation(context.Background(), "create-order"))
+
+	resp, err := client.Do(req)
+	require.NoError(t, err)
+
+	// Metrics should be recorded when body is read.
+	assert.Empty(t, recorder.Calls())
+	body, err := ioutil.ReadAll(resp.Body)
+	require.NoError(t, err)
+	assert.Equal(t, "hello", string(body))
+	require.NoError(t, resp.Body.Close())
+
+	calls := recorder.Calls()
+	require.Len(t, calls, 1)
+	assert.Equal(t, metrics.HTTPClientReqProperties{
+		Host:      strings.TrimPrefix(server.URL, "http://"),
+		Operation: "create-order",
+		Method:    "POST",
+		Code:      "202",
+		Error:     "none",
+	}, calls[0].Props)
+	assert.Equal(t, 5, calls[0].BytesRead)
+
+	// Metrics should be recorded when body is closed without reading.
+	recorder.Reset()
+	resp, err = client.Get(server.URL)
+	require.NoError(t, err)
+	require.NoError(t, resp.Body.Close())
+
+	calls = recorder.Calls()
+	require.Len(t, calls, 1)
+	assert.Equal(t, "none", calls[0].Props.Operation)
+	assert.Equal(t, 0, calls[0].BytesRead)
+}
+
+func TestTransportErrors(t *testing.T) {
+	testCases := []struct {
+		name     string
+		err      error
+		expError string
+	}{
+		{
+			name:     "Canceled requests should be classified.",
+			err:      context.Canceled,
+			expError: "canceled",
+		},
+		{
+			name:     "Deadlines should be classified as timeouts.",
+			err:      &url.Error{Op: "Get", URL: "http://example.com", Err: context.DeadlineExceeded},
+			expError: "timeout",
+		},
+		{
+			name:     "Network timeouts should be classified.",
+			err:      &net.OpError{Op: "dial", Net: "tcp", Err: os.NewSyscallError("connect", syscall.ETIMEDOUT)},
+			expError: "timeout",
+		},
+		{
+			name:     "DNS failures should be classified.",
+			err:      &net.OpError{Op: "dial", Net: "tcp", Err: &net.DNSError{Err: "no such host", Name: "example.invalid"}},
+			expError: "dns",
+		},
+		{
+			name:     "Refused connections should be classified.",
+			err:      &net.OpError{Op: "dial", Net: "tcp", Err: os.NewSyscallError("connect", syscall.ECONNREFUSED)},
+			expError: "connection_refused",
+		},
+		{
+			name:     "Reset connections should be classified.",
+			err:      &net.OpError{Op: "read", Net: "tcp", Err: os.NewSyscallError("read", syscall.ECONNRESET)},
+			expError: "connection_reset",
+		},
+		{
+			name:     "Certificate errors should be classified as TLS errors.",
+			err:      x509.UnknownAuthorityError{},
+			expError: "tls",
+		},
+		{
+			name:     "Malformed TLS records should be classified as TLS errors.",
+			err:      &url.Error{Op: "Get", URL: "https://example.com/", Err: tls.RecordHeaderError{Msg: "first record does not look like a TLS handshake"}},
+			expError: "tls",
+		},
+		{
+			name:     "Errors should not be classified as TLS errors by their messages.",
+			err:      errors.New("tls: unexpected message"),
+			expError: "other",
+		},
+		{
+			name:     "Other errors should not be labelled with their messages.",
+			err:      errors.New("something went wrong"),
+			expError: "other",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			recorder := metricstest.NewHttpClientRecorder()
+			transport := httpmetrics.NewTransport(roundTripperFunc(func(*http.Request) (*http.Response, error) {
+				return nil, tc.err
+			}), recorder)
+
+			_, err := transport.RoundTrip(httptest.NewRequest("GET", "http://example.com/", nil))
+			assert.Equal(t, tc.err, err)
+
+			calls := recorder.Calls()
+			require.Len(t, calls, 1)
+			assert.Equal(t, tc.expError, calls[0].Props.Error)
+			assert.Equal(t, "none", calls[0].Props.Code)
+		})
+	}
+}
+
+func TestTransportServerErrors(t *testing.T) {
+	tlsServer := httptest.NewTLSServer(http.NotFoundHandler())
+	defer tlsServer.Close()
+
+	// Take address of the closed listener for refused connections.
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	closedURL := "http://" + listener.Addr().String()
+	require.NoError(t, listener.Close())
+
+	slowServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-r.Context().Done()
+	}))
+	defer slowServer.Close()
+
+	registry := prometheus.NewRegistry()
+	recorder := httpmetrics.NewHttpClientRecorder("test-app", httpmetrics.Config{Options: metrics.Options{Registerer: registry}})
+	client := &http.Client{Transport: httpmetrics.NewTransport(&http.Transport{}, recorder)}
+
+	_, err = client.Get(tlsServer.URL)
+	assert.Error(t, err)
+
+	_, err = client.Get(closedURL)
+	assert.Error(t, err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	req, err := http.NewRequest("GET", slowServer.URL, nil)
+	require.NoError(t, err)
+	_, err = client.Do(req.WithContext(ctx))
+	assert.Error(t, err)
+
+	metricstest.AssertCounter(t, registry, "app_http_client_requests_total", prometheus.Labels{"error": "tls"}, 1)
+	metricstest.AssertCounter(t, registry, "app_http_client_requests_total", prometheus.Labels{"error": "connection_refused"}, 1)
+	metricstest.AssertCounter(t, registry, "app_http_client_requests_total", prometheus.Labels{"error": "timeout"}, 1)
+	metricstest.AssertHistogramCount(t, registry, "app_http_client_request_duration_seconds", prometheus.Labels{"status": "none"}, 3)
+	metricstest.AssertHistogramCount(t, registry, "app_http_client_response_size_bytes", nil, 0)
+}
+
+func TestTransportSwitchingProtocols(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, buf, err := w.(http.Hijacker).Hijack()
+		require.NoError(t, err)
+		defer func() { _ = conn.Close() }()
+
+		_, _ = buf.WriteString("HTTP/1.1 101 Switching Protocols\r\nConnection: Upgrade\r\nUpgrade: echo\r\n\r\n")
+		_ = buf.Flush()
+		_, _ = io.Copy(conn, buf)
+	}))
+	defer server.Close()
+
+	recorder := metricstest.NewHttpClientRecorder()
+	client := &http.Client{Transport: httpmetrics.NewTransport(&http.Transport{}, recorder)}
+
+	req, err := http.NewRequest("GET", server.URL, nil)
+	require.NoError(t, err)
+	req.Header.Set("Connection", "Upgrade")
+	req.Header.Set("Upgrade", "echo")
+
+	resp, err := client.Do(req)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusSwitchingProtocols, resp.StatusCode)
+
+	// Body of upgraded connection should stay writable.
+	rw, ok := resp.Body.(io.ReadWriteCloser)
+	require.True(t, ok)
+	_, err = rw.Write([]byte("ping"))
+	require.NoError(t, err)
+
+	buf := make([]byte, 4)
+	_, err = io.ReadFull(rw, buf)
+	require.NoError(t, err)
+	assert.Equal(t, "ping", string(buf))
+	require.NoError(t, rw.Close())
+
+	calls := recorder.Calls()
+	require.Len(t, calls, 1)
+	assert.Equal(t, "101", calls[0].Props.Code)
+	assert.Equal(t, 4, calls[0].BytesRead)
+}
+
+func TestRegisterHttpClientRecorder(t *testing.T) {
+	registry := prometheus.NewRegistry()
+	recorder, err := httpmetrics.RegisterHttpClientRecorder("test-app", httpmetrics.Config{Options: metrics.Options{Registerer: registry}})
+	require.NoError(t, err)
+
+	// Server and client recorders should not collide in the same registry.
+	_, err = httpmetrics.RegisterHttpRecorder("test-app", httpmetrics.Config{Options: metrics.Options{Registerer: registry}})
+	require.NoError(t, err)
+
+	recorder.Collect(metrics.HTTPClientReqProperties{Host: "api.example.com", Operation: "get-user", Method: "GET", Code: "200", Error: "none"}, time.Second, 100)
+
+	metricstest.AssertCounter(t, registry, "app_http_client_requests_total", prometheus.Labels{
+		"application": "test-app",
+		"host":        "api.example.com",
+		"operation":   "get-user",
+		"method":      "GET",
+		"status":      "200",
+		"error":       "none",
+	}, 1)
+	metricstest.AssertHistogramCount(t, registry, "app_http_client_response_size_bytes", prometheus.Labels{"host": "api.example.com"}, 1)
+
+	// Constant labels should not collide with variable labels.
+	_, err = httpmetrics.RegisterHttpClientRecorder("test-app", httpmetrics.Config{
+		Options: metrics.Options{ConstLabels: prometheus.Labels{"host": "api"}, Registerer: prometheus.NewRegistry()},
+	})
+	assert.Error(t, err)
+}
@@ -12,17 +12,65 @@ import (
 
 // HTTPReqProperties describes properties of HTTP requests.
 type HTTPReqProperties struct {
-	Path   string // URL Path of the request.
+	Path      string // URL Path of the request.
+	Method    string // Method of the request.
+	Code      string // Response code is the request.
+	BytesRead int    // Number of bytes read from the request body.
+}
+
+// HTTPPanicProperties describes properties of panics occurred in HTTP handlers.
+type HTTPPanicProperties struct {
+	Path   string // Path of the request resolved by PathResolver, e.g. route template.
 	Method string // Method of the request.
-	Code   string // Response code is the request.
+	Kind   string // Kind of the panic: 'panic', or 'abort' for http.ErrAbortHandler.
 }
 
-// HttpRecorder knows how to record and measure HTTP metrics.
+// HttpRecorder knows how to record and measure HTTP metrics. Recorders may implement HttpInFlightRecorder and
+// HttpPanicRecorder for recording requests in flight and panics of handlers.
 type HttpRecorder interface {
 	Collect(props HTTPReqProperties, duration time.Duration, bytesWritten int)
 	Unregister()
 }
 
+// HttpInFlightRecorder is implemented by HTTP recorders which record requests in flight.
+type HttpInFlightRecorder interface {
+	CollectInFlight(props HTTPReqProperties, delta int)
+}
+
+// HttpPanicRecorder is implemented by HTTP recorders which record panics of handlers.
+type HttpPanicRecorder interface {
+	CollectPanic(props HTTPPanicProperties)
+}
+
+// HTTPClientReqProperties describes properties of outbound HTTP requests.
+type HTTPClientReqProperties struct {
+	Host      string // Host of the request URL.
+	Operation string // Name of the operation provided by caller.
+	Method    string // Method of the request.
+	Code      string // Response code of the request, 'none' if response has not been received.
+	Error     string // Class of the transport error, 'none' if request has succeeded.
+}
+
+// HTTPClientPhaseProperties describes properties of phases of outbound HTTP requests.
+type HTTPClientPhaseProperties struct {
+	Host  string // Host of the request URL.
+	Phase string // Phase of the request: 'dns', 'connect', 'tls', 'first_byte' or 'transfer'.
+}
+
+// HTTPClientConnProperties describes properties of connections obtained for outbound HTTP requests.
+type HTTPClientConnProperties struct {
+	Host       string // Host of the request URL.
+	Connection string // Whether connection is 'new' or 'reused'.
+}
+
+// HttpClientRecorder knows how to record and measure metrics of outbound HTTP requests.
+type HttpClientRecorder interface {
+	Collect(props HTTPClientReqProperties, duration time.Duration, bytesRead int)
+	CollectPhase(props HTTPClientPhaseProperties, duration time.Duration)
+	CollectConnection(props HTTPClientConnProperties)
+	Unregister()
+}
+
 /*
  * Redis metrics recorder
  */
@@ -32,22 +80,104 @@ type RedisReqProperties struct {
 	Keyspace string // Key space of the request (if key has been specified).
 	Command  string // Command of the request.
 	Code     string // Response code is the request.
+	Node     string // Address of the node which served the request (if known).
+	Hits     int    // Number of keys or fields found by the read command.
+	Misses   int    // Number of keys or fields not found by the read command.
+	Script   string // Name of the Lua script executed by EVAL or EVALSHA command.
+	NoScript bool   // Whether EVALSHA failed because the script is missing in the script cache.
+
+	RequestSize int // Estimated size of arguments of the request in bytes.
+	ReplySize   int // Estimated size of the reply in bytes, negative if the reply has not been received or is unknown.
+}
+
+// RedisPipelineProperties describes properties of Redis pipelines.
+type RedisPipelineProperties struct {
+	Type string // Type of the pipeline, 'pipeline' or 'tx' for MULTI/EXEC transactions.
+	Code string // Response code of the pipeline.
+	Node string // Address of the node which served the pipeline (if known).
+}
+
+// RedisDialProperties describes properties of dials of connections to Redis nodes.
+type RedisDialProperties struct {
+	Node string // Address of the node.
+	Code string // Response code of the dial.
 }
 
-// RedisRecorder knows how to record and measure Redis metrics.
+// RedisTopologyProperties describes events of topology of Redis deployments.
+type RedisTopologyProperties struct {
+	Node  string // Address of the node.
+	Event string // Event, 'moved' or 'ask' redirect, 'slots_reload' of cluster or 'failover' to the node.
+}
+
+// RedisPubSubProperties describes properties of Pub/Sub messages and subscriptions.
+type RedisPubSubProperties struct {
+	Channel string // Channel or pattern of the message or subscription.
+	Code    string // Response code of publishing of the message.
+}
+
+// RedisRecorder knows how to record and measure Redis metrics. Recorders may implement RedisPipelineRecorder,
+// RedisDialRecorder, RedisTopologyRecorder and RedisPubSubRecorder for recording the rest of Redis metrics.
 type RedisRecorder interface {
 	NewCollectHook() redis.Hook
 	Collect(props RedisReqProperties, duration time.Duration)
 	Unregister()
 }
 
+// RedisPipelineRecorder is implemented by Redis recorders which record pipelines, commands of pipelines are passed
+// to Collect of other recorders.
+type RedisPipelineRecorder interface {
+	CollectPipeline(props RedisPipelineProperties, cmds []RedisReqProperties, duration time.Duration)
+}
+
+// RedisDialRecorder is implemented by Redis recorders which record dials of connections.
+type RedisDialRecorder interface {
+	CollectDial(props RedisDialProperties, duration time.Duration)
+}
+
+// RedisTopologyRecorder is implemented by Redis recorders which record events of topology.
+type RedisTopologyRecorder interface {
+	CollectTopology(props RedisTopologyProperties)
+}
+
+// RedisPubSubRecorder is implemented by Redis recorders which record Pub/Sub messages and subscriptions.
+type RedisPubSubRecorder interface {
+	CollectPublish(props RedisPubSubProperties)
+	CollectReceive(props RedisPubSubProperties, lag time.Duration)
+	CollectSubscriptions(props RedisPubSubProperties, delta int)
+	CollectPubSubReconnect()
+}
+
 /*
  * Postgres metrics recorder
  */
 
-// PostgresRecorder knows how to record and measure Postgres metrics.
+// PostgresQueryProperties describes properties of Postgres queries.
+type PostgresQueryProperties struct {
+	Query   string // Name of the query, provided by caller or fingerprint of the normalized SQL.
+	Outcome string // Outcome of the query: 'ok', 'error' or 'canceled'.
+}
+
+// PostgresTxProperties describes properties of Postgres transactions.
+type PostgresTxProperties struct {
+	Isolation string // Isolation level of the transaction, e.g. 'serializable', or 'default'.
+	Outcome   string // Outcome of the transaction: 'commit', 'rollback', 'commit_failed' or 'serialization_failure'.
+}
+
+// PostgresRecorder knows how to record and measure Postgres metrics. Recorders may implement PostgresQueryRecorder
+// for recording queries and PostgresTxRecorder for recording transactions.
 type PostgresRecorder interface {
 	AfterReleaseHook(conn *pgx.Conn) bool
 	Collect()
 	Unregister()
 }
+
+// PostgresQueryRecorder is implemented by Postgres recorders which record queries.
+type PostgresQueryRecorder interface {
+	NewQueryLogger(next pgx.Logger) pgx.Logger
+	CollectQuery(props PostgresQueryProperties, duration time.Duration, rows int64)
+}
+
+// PostgresTxRecorder is implemented by Postgres recorders which record transactions.
+type PostgresTxRecorder interface {
+	CollectTx(props PostgresTxProperties, duration time.Duration, statements int)
+}
@@ -0,0 +1,251 @@
+package metricstest
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"sort"
+	"strings"
+)
+
+// TestingT is the part of testing.TB used by helpers, hence they could be used with other testing frameworks too.
+type TestingT interface {
+	Helper()
+	Errorf(format string, args ...interface{})
+}
+
+// Gather gathers metrics from g and returns metric families by their names, it fails the test and returns nil if
+// gathering fails.
+func Gather(t TestingT, g prometheus.Gatherer) map[string]*dto.MetricFamily {
+	t.Helper()
+
+	families, err := g.Gather()
+	if err != nil {
+		t.Errorf("gathering metrics: %s", err)
+		return nil
+	}
+
+	result := make(map[string]*dto.MetricFamily, len(families))
+	for _, family := range families {
+		result[family.GetName()] = family
+	}
+
+	return result
+}
+
+// CounterValue returns the sum of values of counters with passed name having all passed labels, other labels of the
+// counters are ignored. Zero is returned if there are no such counters.
+func CounterValue(t TestingT, g prometheus.Gatherer, name string, labels prometheus.Labels) float64 {
+	t.Helper()
+
+	var value float64
+	for _, m := range find(t, g, name, dto.MetricType_COUNTER, labels) {
+		value += m.GetCounter().GetValue()
+	}
+
+	return value
+}
+
+// GaugeValue returns the sum of values of gauges with passed name having all passed labels, other labels of the
+// gauges are ignored. Zero is returned if there are no such gauges.
+func GaugeValue(t TestingT, g prometheus.Gatherer, name string, labels prometheus.Labels) float64 {
+	t.Helper()
+
+	var value float64
+	for _, m := range find(t, g, name, dto.MetricType_GAUGE, labels) {
+		value += m.GetGauge().GetValue()
+	}
+
+	return value
+}
+
+// HistogramCount returns the total number of observations of histograms with passed name having all passed labels,
+// other labels of the histograms are ignored. Zero is returned if there are no such histograms.
+func HistogramCount(t TestingT, g prometheus.Gatherer, name string, labels prometheus.Labels) uint64 {
+	t.Helper()
+
+	var count uint64
+	for _, m := range find(t, g, name, dto.MetricType_HISTOGRAM, labels) {
+		count += m.GetHistogram().GetSampleCount()
+	}
+
+	return count
+}
+
+// HistogramSum returns the total sum of observations of histograms with passed name having all passed labels, other
+// labels of the histograms are ignored. Zero is returned if there are no such histograms.
+func HistogramSum(t TestingT, g prometheus.Gatherer, name string, labels prometheus.Labels) float64 {
+	t.Helper()
+
+	var sum float64
+	for _, m := range find(t, g, name, dto.MetricType_HISTOGRAM, labels) {
+		sum += m.GetHistogram().GetSampleSum()
+	}
+
+	return sum
+}
+
+// LabelSets returns label sets of all series of the metric with passed name.
+func LabelSets(t TestingT, g prometheus.Gatherer, name string) []prometheus.Labels {
+	t.Helper()
+
+	family, ok := Gather(t, g)[name]
+	if !ok {
+		return nil
+	}
+
+	sets := make([]prometheus.Labels, 0, len(family.GetMetric()))
+	for _, m := range family.GetMetric() {
+		sets = append(sets, labelsOf(m))
+	}
+
+	return sets
+}
+
+// LabelValues returns sorted distinct values of the label of the metric with passed name.
+func LabelValues(t TestingT, g prometheus.Gatherer, name, label string) []string {
+	t.Helper()
+
+	seen := make(map[string]bool)
+	for _, labels := range LabelSets(t, g, name) {
+		if value, ok := labels[label]; ok {
+			seen[value] = true
+		}
+	}
+
+	values := make([]string, 0, len(seen))
+	for value := range seen {
+		values = append(values, value)
+	}
+	sort.Strings(values)
+
+	return values
+}
+
+// AssertCounter asserts the value of counters with passed name having all passed labels.
+func AssertCounter(t TestingT, g prometheus.Gatherer, name string, labels prometheus.Labels, expected float64) bool {
+	t.Helper()
+
+	if actual := CounterValue(t, g, name, labels); actual != expected {
+		t.Errorf("value of counter %s%v is %v instead of %v", name, labels, actual, expected)
+		return false
+	}
+	return true
+}
+
+// AssertGauge asserts the value of gauges with passed name having all passed labels.
+func AssertGauge(t TestingT, g prometheus.Gatherer, name string, labels prometheus.Labels, expected float64) bool {
+	t.Helper()
+
+	if actual := GaugeValue(t, g, name, labels); actual != expected {
+		t.Errorf("value of gauge %s%v is %v instead of %v", name, labels, actual, expected)
+		return false
+	}
+	return true
+}
+
+// AssertHistogramCount asserts the number of observations of histograms with passed name having all passed labels.
+func AssertHistogramCount(t TestingT, g prometheus.Gatherer, name string, labels prometheus.Labels, expected uint64) bool {
+	t.Helper()
+
+	if actual := HistogramCount(t, g, name, labels); actual != expected {
+		t.Errorf("count of histogram %s%v is %d instead of %d", name, labels, actual, expected)
+		return false
+	}
+	return true
+}
+
+// AssertLabelSets asserts label sets of all series of the metric with passed name regardless of their order.
+func AssertLabelSets(t TestingT, g prometheus.Gatherer, name string, expected ...prometheus.Labels) bool {
+	t.Helper()
+
+	actual := LabelSets(t, g, name)
+	if !equalLabelSets(actual, expected) {
+		t.Errorf("label sets of %s are %v instead of %v", name, actual, expected)
+		return false
+	}
+	return true
+}
+
+// equalLabelSets reports whether a and b contain the same label sets regardless of their order.
+func equalLabelSets(a, b []prometheus.Labels) bool {
+	if len(a) != len(b) {
+		return false
+	}
+
+	counts := make(map[string]int, len(a))
+	for _, labels := range a {
+		counts[labelsKey(labels)]++
+	}
+	for _, labels := range b {
+		key := labelsKey(labels)
+		if counts[key] == 0 {
+			return false
+		}
+		counts[key]--
+	}
+
+	return true
+}
+
+// labelsKey returns a string which uniquely identifies the label set.
+func labelsKey(labels prometheus.Labels) string {
+	names := make([]string, 0, len(labels))
+	for name := range labels {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var b strings.Builder
+	for _, name := range names {
+		b.WriteString(name)
+		b.WriteByte(0)
+		b.WriteString(labels[name])
+		b.WriteByte(0)
+	}
+	return b.String()
+}
+
+// find returns series of the metric with passed name and type having all passed labels, it fails the test and returns
+// nil if the metric has another type.
+func find(t TestingT, g prometheus.Gatherer, name string, typ dto.MetricType, labels prometheus.Labels) []*dto.Metric {
+	t.Helper()
+
+	family, ok := Gather(t, g)[name]
+	if !ok {
+		return nil
+	}
+
+	if family.GetType() != typ {
+		t.Errorf("metric %s has type %s instead of %s", name, family.GetType(), typ)
+		return nil
+	}
+
+	var result []*dto.Metric
+	for _, m := range family.GetMetric() {
+		if hasLabels(m, labels) {
+			result = append(result, m)
+		}
+	}
+
+	return result
+}
+
+// hasLabels reports whether series has all passed labels.
+func hasLabels(m *dto.Metric, labels prometheus.Labels) bool {
+	actual := labelsOf(m)
+	for name, value := range labels {
+		if v, ok := actual[name]; !ok || v != value {
+			return false
+		}
+	}
+	return true
+}
+
+// labelsOf returns labels of series.
+func labelsOf(m *dto.Metric) prometheus.Labels {
+	labels := make(prometheus.Labels, len(m.GetLabel()))
+	for _, pair := range m.GetLabel() {
+		labels[pair.GetName()] = pair.GetValue()
+	}
+	return labels
+}
@@ -0,0 +1,72 @@
+package metricstest_test
+
+import (
+	"fmt"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/assert"
+	"github.com/weaponry/go-instrumenting/metrics"
+	httpmetrics "github.com/weaponry/go-instrumenting/metrics/http"
+	"github.com/weaponry/go-instrumenting/metrics/metricstest"
+	"testing"
+	"time"
+)
+
+func TestAssertions(t *testing.T) {
+	registry := prometheus.NewRegistry()
+	recorder := httpmetrics.NewHttpRecorder("test-app", httpmetrics.Config{Options: metrics.Options{Registerer: registry}})
+
+	recorder.Collect(metrics.HTTPReqProperties{Path: "/users", Method: "GET", Code: "200"}, time.Second, 10)
+	recorder.Collect(metrics.HTTPReqProperties{Path: "/users", Method: "GET", Code: "200"}, time.Second, 20)
+	recorder.Collect(metrics.HTTPReqProperties{Path: "/users", Method: "POST", Code: "500"}, time.Second, 30)
+
+	metricstest.AssertCounter(t, registry, "app_http_requests_total", prometheus.Labels{"method": "GET"}, 2)
+	metricstest.AssertCounter(t, registry, "app_http_requests_total", prometheus.Labels{"application": "test-app"}, 3)
+	metricstest.AssertCounter(t, registry, "app_http_requests_total", prometheus.Labels{"method": "PUT"}, 0)
+	metricstest.AssertCounter(t, registry, "app_unknown_total", nil, 0)
+	metricstest.AssertHistogramCount(t, registry, "app_http_response_size_bytes", prometheus.Labels{"path": "/users"}, 3)
+	assert.Equal(t, float64(60), metricstest.HistogramSum(t, registry, "app_http_response_size_bytes", nil))
+
+	metricstest.AssertLabelSets(t, registry, "app_http_requests_total",
+		prometheus.Labels{"application": "test-app", "path": "/users", "method": "POST", "status": "500"},
+		prometheus.Labels{"application": "test-app", "path": "/users", "method": "GET", "status": "200"},
+	)
+	assert.Equal(t, []string{"200", "500"}, metricstest.LabelValues(t, registry, "app_http_requests_total", "status"))
+	assert.Nil(t, metricstest.LabelSets(t, registry, "app_unknown_total"))
+}
+
+func TestAssertGauge(t *testing.T) {
+	registry := prometheus.NewRegistry()
+	gauge := prometheus.NewGaugeVec(prometheus.GaugeOpts{Name: "connections"}, []string{"node"})
+	registry.MustRegister(gauge)
+
+	gauge.WithLabelValues("a").Set(2)
+	gauge.WithLabelValues("b").Set(3)
+
+	metricstest.AssertGauge(t, registry, "connections", prometheus.Labels{"node": "a"}, 2)
+	metricstest.AssertGauge(t, registry, "connections", nil, 5)
+}
+
+// recordingT records failures of assertions instead of failing the test.
+type recordingT struct {
+	errors []string
+}
+
+func (t *recordingT) Helper() {}
+
+func (t *recordingT) Errorf(format string, args ...interface{}) {
+	t.errors = append(t.errors, fmt.Sprintf(format, args...))
+}
+
+func TestAssertionsFailure(t *testing.T) {
+	registry := prometheus.NewRegistry()
+	counter := prometheus.NewCounterVec(prometheus.CounterOpts{Name: "requests_total"}, []string{"code"})
+	registry.MustRegister(counter)
+	counter.WithLabelValues("200").Inc()
+
+	rt := &recordingT{}
+	assert.False(t, metricstest.AssertCounter(rt, registry, "requests_total", nil, 2))
+	assert.False(t, metricstest.AssertGauge(rt, registry, "requests_total", nil, 1), "type of metric should be checked")
+	assert.False(t, metricstest.AssertLabelSets(rt, registry, "requests_total", prometheus.Labels{"code": "500"}))
+	assert.True(t, metricstest.AssertLabelSets(rt, registry, "requests_total", prometheus.Labels{"code": "200"}))
+	assert.Len(t, rt.errors, 4)
+}
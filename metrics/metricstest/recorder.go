@@ -0,0 +1,603 @@
+// Package metricstest provides fake recorders and helpers for asserting metrics in unit tests.
+package metricstest
+
+import (
+	"github.com/go-redis/redis/v7"
+	"github.com/jackc/pgx/v4"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/weaponry/go-instrumenting/metrics"
+	postgresmetrics "github.com/weaponry/go-instrumenting/metrics/postgres"
+	redismetrics "github.com/weaponry/go-instrumenting/metrics/redis"
+	"sync"
+	"time"
+)
+
+// CallsMetric is a name of the counter of calls of Collect* methods of fake recorders labelled by 'method', it is
+// gathered from registries returned by their Gatherer methods.
+const CallsMetric = "metricstest_calls_total"
+
+// calls counts calls of methods of a fake recorder in its own registry.
+type calls struct {
+	once     sync.Once
+	registry *prometheus.Registry
+	counter  *prometheus.CounterVec
+}
+
+func (c *calls) init() {
+	c.once.Do(func() {
+		c.registry = prometheus.NewRegistry()
+		c.counter = prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: CallsMetric,
+			Help: "The total number of calls of methods of the fake recorder.",
+		}, []string{"method"})
+		c.registry.MustRegister(c.counter)
+	})
+}
+
+// inc counts a call of the method.
+func (c *calls) inc(method string) {
+	c.init()
+	c.counter.WithLabelValues(method).Inc()
+}
+
+// reset forgets counted calls.
+func (c *calls) reset() {
+	c.init()
+	c.counter.Reset()
+}
+
+// gatherer returns the registry of counted calls.
+func (c *calls) gatherer() prometheus.Gatherer {
+	c.init()
+	return c.registry
+}
+
+/*
+ * HTTP recorder
+ */
+
+// HttpCall describes a call of HttpRecorder.Collect.
+type HttpCall struct {
+	Props        metrics.HTTPReqProperties
+	Duration     time.Duration
+	BytesWritten int
+}
+
+// HttpRecorder is a fake metrics.HttpRecorder which records all calls of Collect and CollectPanic and keeps track of
+// requests in flight. It is safe for concurrent use.
+type HttpRecorder struct {
+	mu       sync.Mutex
+	counted  calls
+	calls    []HttpCall
+	inFlight int
+	panics   []metrics.HTTPPanicProperties
+}
+
+// NewHttpRecorder creates fake HTTP recorder.
+func NewHttpRecorder() *HttpRecorder {
+	return &HttpRecorder{}
+}
+
+// Collect records the call.
+func (r *HttpRecorder) Collect(props metrics.HTTPReqProperties, duration time.Duration, bytesWritten int) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.counted.inc("Collect")
+	r.calls = append(r.calls, HttpCall{Props: props, Duration: duration, BytesWritten: bytesWritten})
+}
+
+// CollectInFlight changes the number of requests in flight by delta.
+func (r *HttpRecorder) CollectInFlight(_ metrics.HTTPReqProperties, delta int) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.counted.inc("CollectInFlight")
+	r.inFlight += delta
+}
+
+// CollectPanic records the call.
+func (r *HttpRecorder) CollectPanic(props metrics.HTTPPanicProperties) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.counted.inc("CollectPanic")
+	r.panics = append(r.panics, props)
+}
+
+// Calls returns recorded calls of Collect in order they were made.
+func (r *HttpRecorder) Calls() []HttpCall {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return append([]HttpCall(nil), r.calls...)
+}
+
+// InFlight returns the number of requests in flight.
+func (r *HttpRecorder) InFlight() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.inFlight
+}
+
+// Panics returns properties passed to CollectPanic in order they were passed.
+func (r *HttpRecorder) Panics() []metrics.HTTPPanicProperties {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return append([]metrics.HTTPPanicProperties(nil), r.panics...)
+}
+
+// Reset forgets recorded calls.
+func (r *HttpRecorder) Reset() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.counted.reset()
+	r.calls = nil
+	r.inFlight = 0
+	r.panics = nil
+}
+
+// Gatherer returns the registry of the fake recorder, which counts calls of Collect* methods in CallsMetric.
+func (r *HttpRecorder) Gatherer() prometheus.Gatherer {
+	return r.counted.gatherer()
+}
+
+// Unregister does nothing.
+func (r *HttpRecorder) Unregister() {}
+
+// HttpClientCall describes a call of HttpClientRecorder.Collect.
+type HttpClientCall struct {
+	Props     metrics.HTTPClientReqProperties
+	Duration  time.Duration
+	BytesRead int
+}
+
+// HttpClientPhaseCall describes a call of HttpClientRecorder.CollectPhase.
+type HttpClientPhaseCall struct {
+	Props    metrics.HTTPClientPhaseProperties
+	Duration time.Duration
+}
+
+// HttpClientRecorder is a fake metrics.HttpClientRecorder which records all calls of Collect, CollectPhase and
+// CollectConnection. It is safe for concurrent use.
+type HttpClientRecorder struct {
+	mu              sync.Mutex
+	counted         calls
+	calls           []HttpClientCall
+	phaseCalls      []HttpClientPhaseCall
+	connectionCalls []metrics.HTTPClientConnProperties
+}
+
+// NewHttpClientRecorder creates fake recorder of outbound HTTP requests.
+func NewHttpClientRecorder() *HttpClientRecorder {
+	return &HttpClientRecorder{}
+}
+
+// Collect records the call.
+func (r *HttpClientRecorder) Collect(props metrics.HTTPClientReqProperties, duration time.Duration, bytesRead int) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.counted.inc("Collect")
+	r.calls = append(r.calls, HttpClientCall{Props: props, Duration: duration, BytesRead: bytesRead})
+}
+
+// CollectPhase records the call.
+func (r *HttpClientRecorder) CollectPhase(props metrics.HTTPClientPhaseProperties, duration time.Duration) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.counted.inc("CollectPhase")
+	r.phaseCalls = append(r.phaseCalls, HttpClientPhaseCall{Props: props, Duration: duration})
+}
+
+// CollectConnection records the call.
+func (r *HttpClientRecorder) CollectConnection(props metrics.HTTPClientConnProperties) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.counted.inc("CollectConnection")
+	r.connectionCalls = append(r.connectionCalls, props)
+}
+
+// Calls returns recorded calls of Collect in order they were made.
+func (r *HttpClientRecorder) Calls() []HttpClientCall {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return append([]HttpClientCall(nil), r.calls...)
+}
+
+// PhaseCalls returns recorded calls of CollectPhase in order they were made.
+func (r *HttpClientRecorder) PhaseCalls() []HttpClientPhaseCall {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return append([]HttpClientPhaseCall(nil), r.phaseCalls...)
+}
+
+// ConnectionCalls returns properties passed to CollectConnection in order they were passed.
+func (r *HttpClientRecorder) ConnectionCalls() []metrics.HTTPClientConnProperties {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return append([]metrics.HTTPClientConnProperties(nil), r.connectionCalls...)
+}
+
+// Reset forgets recorded calls.
+func (r *HttpClientRecorder) Reset() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.counted.reset()
+	r.calls = nil
+	r.phaseCalls = nil
+	r.connectionCalls = nil
+}
+
+// Gatherer returns the registry of the fake recorder, which counts calls of Collect* methods in CallsMetric.
+func (r *HttpClientRecorder) Gatherer() prometheus.Gatherer {
+	return r.counted.gatherer()
+}
+
+// Unregister does nothing.
+func (r *HttpClientRecorder) Unregister() {}
+
+/*
+ * Redis recorder
+ */
+
+// RedisCall describes a call of RedisRecorder.Collect.
+type RedisCall struct {
+	Props    metrics.RedisReqProperties
+	Duration time.Duration
+}
+
+// RedisPipelineCall describes a call of RedisRecorder.CollectPipeline.
+type RedisPipelineCall struct {
+	Props    metrics.RedisPipelineProperties
+	Cmds     []metrics.RedisReqProperties
+	Duration time.Duration
+}
+
+// RedisDialCall describes a call of RedisRecorder.CollectDial.
+type RedisDialCall struct {
+	Props    metrics.RedisDialProperties
+	Duration time.Duration
+}
+
+// RedisReceiveCall describes a call of RedisRecorder.CollectReceive.
+type RedisReceiveCall struct {
+	Props metrics.RedisPubSubProperties
+	Lag   time.Duration
+}
+
+// RedisRecorder is a fake metrics.RedisRecorder which records all calls of its Collect* methods. It is safe for
+// concurrent use.
+type RedisRecorder struct {
+	mu            sync.Mutex
+	counted       calls
+	calls         []RedisCall
+	pipelineCalls []RedisPipelineCall
+	dialCalls     []RedisDialCall
+	topology      []metrics.RedisTopologyProperties
+	published     []metrics.RedisPubSubProperties
+	receiveCalls  []RedisReceiveCall
+	subscriptions map[string]int
+	reconnects    int
+}
+
+// NewRedisRecorder creates fake Redis recorder.
+func NewRedisRecorder() *RedisRecorder {
+	return &RedisRecorder{}
+}
+
+// NewCollectHook returns hook which passes properties of processed commands to the fake recorder.
+func (r *RedisRecorder) NewCollectHook() redis.Hook {
+	return redismetrics.NewCollectHook(r)
+}
+
+// Collect records the call.
+func (r *RedisRecorder) Collect(props metrics.RedisReqProperties, duration time.Duration) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.counted.inc("Collect")
+	r.calls = append(r.calls, RedisCall{Props: props, Duration: duration})
+}
+
+// CollectPipeline records the call.
+func (r *RedisRecorder) CollectPipeline(props metrics.RedisPipelineProperties, cmds []metrics.RedisReqProperties, duration time.Duration) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.counted.inc("CollectPipeline")
+	cmds = append([]metrics.RedisReqProperties(nil), cmds...)
+	r.pipelineCalls = append(r.pipelineCalls, RedisPipelineCall{Props: props, Cmds: cmds, Duration: duration})
+}
+
+// CollectDial records the call.
+func (r *RedisRecorder) CollectDial(props metrics.RedisDialProperties, duration time.Duration) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.counted.inc("CollectDial")
+	r.dialCalls = append(r.dialCalls, RedisDialCall{Props: props, Duration: duration})
+}
+
+// CollectTopology records the call.
+func (r *RedisRecorder) CollectTopology(props metrics.RedisTopologyProperties) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.counted.inc("CollectTopology")
+	r.topology = append(r.topology, props)
+}
+
+// CollectPublish records the call.
+func (r *RedisRecorder) CollectPublish(props metrics.RedisPubSubProperties) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.counted.inc("CollectPublish")
+	r.published = append(r.published, props)
+}
+
+// CollectReceive records the call.
+func (r *RedisRecorder) CollectReceive(props metrics.RedisPubSubProperties, lag time.Duration) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.counted.inc("CollectReceive")
+	r.receiveCalls = append(r.receiveCalls, RedisReceiveCall{Props: props, Lag: lag})
+}
+
+// CollectSubscriptions changes the number of active subscriptions to the channel by delta.
+func (r *RedisRecorder) CollectSubscriptions(props metrics.RedisPubSubProperties, delta int) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.counted.inc("CollectSubscriptions")
+	if r.subscriptions == nil {
+		r.subscriptions = make(map[string]int)
+	}
+	r.subscriptions[props.Channel] += delta
+	if r.subscriptions[props.Channel] == 0 {
+		delete(r.subscriptions, props.Channel)
+	}
+}
+
+// CollectPubSubReconnect records the call.
+func (r *RedisRecorder) CollectPubSubReconnect() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.counted.inc("CollectPubSubReconnect")
+	r.reconnects++
+}
+
+// Calls returns recorded calls of Collect in order they were made.
+func (r *RedisRecorder) Calls() []RedisCall {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return append([]RedisCall(nil), r.calls...)
+}
+
+// PipelineCalls returns recorded calls of CollectPipeline in order they were made.
+func (r *RedisRecorder) PipelineCalls() []RedisPipelineCall {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return append([]RedisPipelineCall(nil), r.pipelineCalls...)
+}
+
+// DialCalls returns recorded calls of CollectDial in order they were made.
+func (r *RedisRecorder) DialCalls() []RedisDialCall {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return append([]RedisDialCall(nil), r.dialCalls...)
+}
+
+// TopologyEvents returns properties passed to CollectTopology in order they were passed.
+func (r *RedisRecorder) TopologyEvents() []metrics.RedisTopologyProperties {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return append([]metrics.RedisTopologyProperties(nil), r.topology...)
+}
+
+// Published returns properties passed to CollectPublish in order they were passed.
+func (r *RedisRecorder) Published() []metrics.RedisPubSubProperties {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return append([]metrics.RedisPubSubProperties(nil), r.published...)
+}
+
+// ReceiveCalls returns recorded calls of CollectReceive in order they were made.
+func (r *RedisRecorder) ReceiveCalls() []RedisReceiveCall {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return append([]RedisReceiveCall(nil), r.receiveCalls...)
+}
+
+// Subscriptions returns the current numbers of active subscriptions by channels, channels without subscriptions are
+// omitted.
+func (r *RedisRecorder) Subscriptions() map[string]int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	subscriptions := make(map[string]int, len(r.subscriptions))
+	for channel, n := range r.subscriptions {
+		subscriptions[channel] = n
+	}
+	return subscriptions
+}
+
+// PubSubReconnects returns the number of calls of CollectPubSubReconnect.
+func (r *RedisRecorder) PubSubReconnects() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.reconnects
+}
+
+// Reset forgets recorded calls.
+func (r *RedisRecorder) Reset() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.counted.reset()
+	r.calls = nil
+	r.pipelineCalls = nil
+	r.dialCalls = nil
+	r.topology = nil
+	r.published = nil
+	r.receiveCalls = nil
+	r.subscriptions = nil
+	r.reconnects = 0
+}
+
+// Gatherer returns the registry of the fake recorder, which counts calls of Collect* methods in CallsMetric.
+func (r *RedisRecorder) Gatherer() prometheus.Gatherer {
+	return r.counted.gatherer()
+}
+
+// Unregister does nothing.
+func (r *RedisRecorder) Unregister() {}
+
+/*
+ * Postgres recorder
+ */
+
+// PostgresQueryCall describes a call of PostgresRecorder.CollectQuery.
+type PostgresQueryCall struct {
+	Props    metrics.PostgresQueryProperties
+	Duration time.Duration
+	Rows     int64
+}
+
+// PostgresTxCall describes a call of PostgresRecorder.CollectTx.
+type PostgresTxCall struct {
+	Props      metrics.PostgresTxProperties
+	Duration   time.Duration
+	Statements int
+}
+
+// PostgresRecorder is a fake metrics.PostgresRecorder which records all calls of Collect, CollectQuery and
+// CollectTx. It is safe for concurrent use.
+type PostgresRecorder struct {
+	mu         sync.Mutex
+	counted    calls
+	collects   int
+	queryCalls []PostgresQueryCall
+	txCalls    []PostgresTxCall
+}
+
+// NewPostgresRecorder creates fake Postgres recorder.
+func NewPostgresRecorder() *PostgresRecorder {
+	return &PostgresRecorder{}
+}
+
+// AfterReleaseHook calls Collect and returns true.
+func (r *PostgresRecorder) AfterReleaseHook(_ *pgx.Conn) bool {
+	r.Collect()
+	return true
+}
+
+// NewQueryLogger returns pgx.Logger which passes metrics of logged queries to the fake recorder.
+func (r *PostgresRecorder) NewQueryLogger(next pgx.Logger) pgx.Logger {
+	return postgresmetrics.NewQueryLogger(next, r)
+}
+
+// Collect records the call.
+func (r *PostgresRecorder) Collect() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.counted.inc("Collect")
+	r.collects++
+}
+
+// CollectQuery records the call.
+func (r *PostgresRecorder) CollectQuery(props metrics.PostgresQueryProperties, duration time.Duration, rows int64) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.counted.inc("CollectQuery")
+	r.queryCalls = append(r.queryCalls, PostgresQueryCall{Props: props, Duration: duration, Rows: rows})
+}
+
+// CollectTx records the call.
+func (r *PostgresRecorder) CollectTx(props metrics.PostgresTxProperties, duration time.Duration, statements int) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.counted.inc("CollectTx")
+	r.txCalls = append(r.txCalls, PostgresTxCall{Props: props, Duration: duration, Statements: statements})
+}
+
+// Collects returns the number of calls of Collect.
+func (r *PostgresRecorder) Collects() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.collects
+}
+
+// QueryCalls returns recorded calls of CollectQuery in order they were made.
+func (r *PostgresRecorder) QueryCalls() []PostgresQueryCall {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return append([]PostgresQueryCall(nil), r.queryCalls...)
+}
+
+// TxCalls returns recorded calls of CollectTx in order they were made.
+func (r *PostgresRecorder) TxCalls() []PostgresTxCall {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return append([]PostgresTxCall(nil), r.txCalls...)
+}
+
+// Reset forgets recorded calls.
+func (r *PostgresRecorder) Reset() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.counted.reset()
+	r.collects = 0
+	r.queryCalls = nil
+	r.txCalls = nil
+}
+
+// Gatherer returns the registry of the fake recorder, which counts calls of Collect* methods in CallsMetric.
+func (r *PostgresRecorder) Gatherer() prometheus.Gatherer {
+	return r.counted.gatherer()
+}
+
+// Unregister does nothing.
+func (r *PostgresRecorder) Unregister() {}
+
+// Interface compliance checks.
+var (
+	_ metrics.HttpRecorder          = (*HttpRecorder)(nil)
+	_ metrics.HttpInFlightRecorder  = (*HttpRecorder)(nil)
+	_ metrics.HttpPanicRecorder     = (*HttpRecorder)(nil)
+	_ metrics.HttpClientRecorder    = (*HttpClientRecorder)(nil)
+	_ metrics.RedisRecorder         = (*RedisRecorder)(nil)
+	_ metrics.RedisPipelineRecorder = (*RedisRecorder)(nil)
+	_ metrics.RedisDialRecorder     = (*RedisRecorder)(nil)
+	_ metrics.RedisTopologyRecorder = (*RedisRecorder)(nil)
+	_ metrics.RedisPubSubRecorder   = (*RedisRecorder)(nil)
+	_ metrics.PostgresRecorder      = (*PostgresRecorder)(nil)
+	_ metrics.PostgresQueryRecorder = (*PostgresRecorder)(nil)
+	_ metrics.PostgresTxRecorder    = (*PostgresRecorder)(nil)
+)
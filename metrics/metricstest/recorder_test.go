@@ -0,0 +1,100 @@
+package metricstest_test
+
+import (
+	"context"
+	"errors"
+	"github.com/go-redis/redis/v7"
+	"github.com/jackc/pgx/v4"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/weaponry/go-instrumenting/metrics"
+	httpmetrics "github.com/weaponry/go-instrumenting/metrics/http"
+	"github.com/weaponry/go-instrumenting/metrics/metricstest"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestHttpRecorder(t *testing.T) {
+	recorder := metricstest.NewHttpRecorder()
+
+	h := httpmetrics.Middleware(recorder)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusCreated)
+		_, _ = w.Write([]byte("hello"))
+	}))
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("POST", "/users/42", nil))
+
+	calls := recorder.Calls()
+	require.Len(t, calls, 1)
+	assert.Equal(t, metrics.HTTPReqProperties{Path: "/users/:id", Method: "POST", Code: "201"}, calls[0].Props)
+	assert.Equal(t, 5, calls[0].BytesWritten)
+
+	// Calls should be counted in the same registry.
+	assert.Equal(t, recorder.Gatherer(), recorder.Gatherer())
+	metricstest.AssertCounter(t, recorder.Gatherer(), metricstest.CallsMetric, prometheus.Labels{"method": "Collect"}, 1)
+	metricstest.AssertCounter(t, recorder.Gatherer(), metricstest.CallsMetric, prometheus.Labels{"method": "CollectInFlight"}, 2)
+
+	recorder.Reset()
+	assert.Empty(t, recorder.Calls())
+	metricstest.AssertCounter(t, recorder.Gatherer(), metricstest.CallsMetric, nil, 0)
+}
+
+func TestRedisRecorder(t *testing.T) {
+	recorder := metricstest.NewRedisRecorder()
+	hook := recorder.NewCollectHook()
+
+	cmd := redis.NewStringCmd("get", "app/users/1")
+	ctx, err := hook.BeforeProcess(context.Background(), cmd)
+	require.NoError(t, err)
+	require.NoError(t, hook.AfterProcess(ctx, cmd))
+
+	cmds := []redis.Cmder{redis.NewStringCmd("get", "app/users/1"), redis.NewStringCmd("get", "app/users/2")}
+	cmds[1].SetErr(errors.New("failed"))
+	ctx, err = hook.BeforeProcessPipeline(context.Background(), cmds)
+	require.NoError(t, err)
+	require.NoError(t, hook.AfterProcessPipeline(ctx, cmds))
+
+	calls := recorder.Calls()
+	require.Len(t, calls, 1)
+	assert.Equal(t, metrics.RedisReqProperties{Keyspace: "/users", Command: "get", Code: "ok", Hits: 1, RequestSize: 14}, calls[0].Props)
+
+	pipelineCalls := recorder.PipelineCalls()
+	require.Len(t, pipelineCalls, 1)
+	assert.Equal(t, metrics.RedisPipelineProperties{Type: "pipeline", Code: "other"}, pipelineCalls[0].Props)
+	assert.Len(t, pipelineCalls[0].Cmds, 2)
+
+	recorder.Reset()
+	assert.Empty(t, recorder.Calls())
+	assert.Empty(t, recorder.PipelineCalls())
+}
+
+func TestPostgresRecorder(t *testing.T) {
+	recorder := metricstest.NewPostgresRecorder()
+
+	assert.True(t, recorder.AfterReleaseHook(nil))
+	assert.Equal(t, 1, recorder.Collects())
+
+	logger := recorder.NewQueryLogger(nil)
+	logger.Log(context.Background(), pgx.LogLevelInfo, "Query", map[string]interface{}{
+		"sql":      "select 1",
+		"time":     time.Millisecond,
+		"rowCount": 1,
+	})
+
+	calls := recorder.QueryCalls()
+	require.Len(t, calls, 1)
+	assert.Equal(t, "ok", calls[0].Props.Outcome)
+	assert.Equal(t, time.Millisecond, calls[0].Duration)
+	assert.Equal(t, int64(1), calls[0].Rows)
+
+	props := metrics.PostgresTxProperties{Isolation: "default", Outcome: "commit"}
+	recorder.CollectTx(props, time.Millisecond, 2)
+	assert.Equal(t, []metricstest.PostgresTxCall{{Props: props, Duration: time.Millisecond, Statements: 2}}, recorder.TxCalls())
+
+	recorder.Reset()
+	assert.Equal(t, 0, recorder.Collects())
+	assert.Empty(t, recorder.QueryCalls())
+	assert.Empty(t, recorder.TxCalls())
+}
@@ -0,0 +1,101 @@
+package metrics
+
+import (
+	"fmt"
+	"github.com/prometheus/client_golang/prometheus"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+)
+
+// LabelApp is a name of the constant label with application name added to all metrics.
+const LabelApp = "application"
+
+// defaultNamespace is a default prefix of metric names.
+const defaultNamespace = "app"
+
+var (
+	metricNameRegexp = regexp.MustCompile(`^[a-zA-Z_:][a-zA-Z0-9_:]*$`)
+	labelNameRegexp  = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]*$`)
+)
+
+// Options describe naming of metrics and labels, shared by configurations of all recorders.
+type Options struct {
+	// Namespace is the first part of metric names, by default 'app'.
+	Namespace string
+	// Subsystem is the second part of metric names, by default depends on recorder, e.g. 'http' or 'redis'.
+	Subsystem string
+	// ConstLabels are labels with constant values added to all metrics in addition to the 'application' label.
+	ConstLabels prometheus.Labels
+	// Registerer is used for registering metrics, by default uses prometheus.DefaultRegisterer.
+	Registerer prometheus.Registerer
+	// Gatherer is used for gathering registered metrics, by default uses Registerer if it is a prometheus.Gatherer
+	// too (e.g. *prometheus.Registry), otherwise prometheus.DefaultGatherer.
+	Gatherer prometheus.Gatherer
+}
+
+// GathererProvider is implemented by recorders which expose the gatherer of their metrics, see Options.Gatherer.
+type GathererProvider interface {
+	Gatherer() prometheus.Gatherer
+}
+
+// SetDefaults sets default namespace, passed subsystem, registerer and gatherer if they are not specified.
+func (o *Options) SetDefaults(subsystem string) {
+	if o.Namespace == "" {
+		o.Namespace = defaultNamespace
+	}
+
+	if o.Subsystem == "" {
+		o.Subsystem = subsystem
+	}
+
+	if o.Registerer == nil {
+		o.Registerer = prometheus.DefaultRegisterer
+	}
+
+	if o.Gatherer == nil {
+		if g, ok := o.Registerer.(prometheus.Gatherer); ok {
+			o.Gatherer = g
+		} else {
+			o.Gatherer = prometheus.DefaultGatherer
+		}
+	}
+}
+
+// Validate checks namespace, subsystem and constant labels against Prometheus naming rules. Variable labels of
+// recorder metrics are passed for checking they don't collide with constant labels.
+func (o Options) Validate(variableLabels ...string) error {
+	if name := prometheus.BuildFQName(o.Namespace, o.Subsystem, "metric"); !metricNameRegexp.MatchString(name) {
+		return fmt.Errorf("invalid namespace %q or subsystem %q", o.Namespace, o.Subsystem)
+	}
+
+	for name, value := range o.ConstLabels {
+		if !labelNameRegexp.MatchString(name) || strings.HasPrefix(name, "__") {
+			return fmt.Errorf("invalid constant label name %q", name)
+		}
+		if !utf8.ValidString(value) {
+			return fmt.Errorf("invalid value of constant label %q", name)
+		}
+		if name == LabelApp {
+			return fmt.Errorf("constant label %q is reserved", name)
+		}
+		for _, variable := range variableLabels {
+			if name == variable {
+				return fmt.Errorf("constant label %q collides with variable label", name)
+			}
+		}
+	}
+
+	return nil
+}
+
+// Labels returns constant labels including the 'application' label with passed application name.
+func (o Options) Labels(appName string) prometheus.Labels {
+	labels := make(prometheus.Labels, len(o.ConstLabels)+1)
+	for name, value := range o.ConstLabels {
+		labels[name] = value
+	}
+	labels[LabelApp] = appName
+
+	return labels
+}
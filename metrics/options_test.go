@@ -0,0 +1,96 @@
+package metrics_test
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/assert"
+	"github.com/weaponry/go-instrumenting/metrics"
+	"testing"
+)
+
+func TestOptionsValidate(t *testing.T) {
+	testCases := []struct {
+		name    string
+		options metrics.Options
+		expErr  bool
+	}{
+		{
+			name:    "Default options should be valid.",
+			options: metrics.Options{},
+		},
+		{
+			name: "Custom namespace, subsystem and labels should be valid.",
+			options: metrics.Options{
+				Namespace:   "acme",
+				Subsystem:   "api_gateway",
+				ConstLabels: prometheus.Labels{"team": "core", "env": "prod", "region": "eu-west-1"},
+			},
+		},
+		{
+			name:    "Namespace with dashes should be invalid.",
+			options: metrics.Options{Namespace: "acme-corp"},
+			expErr:  true,
+		},
+		{
+			name:    "Subsystem with dots should be invalid.",
+			options: metrics.Options{Subsystem: "http.v1"},
+			expErr:  true,
+		},
+		{
+			name:    "Label with invalid name should be invalid.",
+			options: metrics.Options{ConstLabels: prometheus.Labels{"team-name": "core"}},
+			expErr:  true,
+		},
+		{
+			name:    "Label with reserved prefix should be invalid.",
+			options: metrics.Options{ConstLabels: prometheus.Labels{"__team": "core"}},
+			expErr:  true,
+		},
+		{
+			name:    "Application label should be reserved.",
+			options: metrics.Options{ConstLabels: prometheus.Labels{"application": "another"}},
+			expErr:  true,
+		},
+		{
+			name:    "Label colliding with variable label should be invalid.",
+			options: metrics.Options{ConstLabels: prometheus.Labels{"status": "ok"}},
+			expErr:  true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			tc.options.SetDefaults("test")
+			err := tc.options.Validate("path", "status")
+			if tc.expErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestOptionsLabels(t *testing.T) {
+	options := metrics.Options{ConstLabels: prometheus.Labels{"team": "core"}}
+
+	assert.Equal(t, prometheus.Labels{"team": "core", "application": "test-app"}, options.Labels("test-app"))
+	assert.Equal(t, prometheus.Labels{"team": "core"}, options.ConstLabels, "constant labels should not be modified")
+}
+
+func TestOptionsSetDefaults(t *testing.T) {
+	var options metrics.Options
+	options.SetDefaults("test")
+	assert.Equal(t, prometheus.DefaultRegisterer, options.Registerer)
+	assert.Equal(t, prometheus.DefaultGatherer, options.Gatherer)
+
+	// Registry should be used as the gatherer of its metrics.
+	registry := prometheus.NewRegistry()
+	options = metrics.Options{Registerer: registry}
+	options.SetDefaults("test")
+	assert.Equal(t, registry, options.Gatherer)
+
+	// Registerers which don't gather metrics should be gathered by the default gatherer.
+	options = metrics.Options{Registerer: prometheus.WrapRegistererWith(prometheus.Labels{"team": "core"}, registry)}
+	options.SetDefaults("test")
+	assert.Equal(t, prometheus.DefaultGatherer, options.Gatherer)
+}
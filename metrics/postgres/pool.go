@@ -0,0 +1,93 @@
+package postgres
+
+import (
+	"github.com/jackc/pgx/v4/pgxpool"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// poolCollector exports statistics of pgxpool.Pool at scrape time.
+type poolCollector struct {
+	pool *pgxpool.Pool
+
+	totalConns           *prometheus.Desc
+	idleConns            *prometheus.Desc
+	acquiredConns        *prometheus.Desc
+	constructingConns    *prometheus.Desc
+	maxConns             *prometheus.Desc
+	acquireCount         *prometheus.Desc
+	acquireDuration      *prometheus.Desc
+	emptyAcquireCount    *prometheus.Desc
+	canceledAcquireCount *prometheus.Desc
+}
+
+// NewPoolCollector creates collector of pool statistics and registers it, it panics if registration fails.
+func NewPoolCollector(appName string, pool *pgxpool.Pool, config Config) prometheus.Collector {
+	c, err := RegisterPoolCollector(appName, pool, config)
+	if err != nil {
+		panic(err)
+	}
+	return c
+}
+
+// RegisterPoolCollector creates collector of pool statistics and registers it. Use constant labels of config for
+// distinguishing several pools of the application. Collector could be removed with Unregister of config's Registerer.
+func RegisterPoolCollector(appName string, pool *pgxpool.Pool, config Config) (prometheus.Collector, error) {
+	config.defaults()
+
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+
+	constLabels := config.Labels(appName)
+	desc := func(name, help string) *prometheus.Desc {
+		return prometheus.NewDesc(prometheus.BuildFQName(config.Namespace, config.Subsystem, name), help, nil, constLabels)
+	}
+
+	c := &poolCollector{
+		pool:                 pool,
+		totalConns:           desc("pool_total_connections", "The total number of connections in the pool."),
+		idleConns:            desc("pool_idle_connections", "The number of idle connections in the pool."),
+		acquiredConns:        desc("pool_acquired_connections", "The number of currently acquired connections in the pool."),
+		constructingConns:    desc("pool_constructing_connections", "The number of connections with construction in progress in the pool."),
+		maxConns:             desc("pool_max_connections", "The maximum size of the pool."),
+		acquireCount:         desc("pool_acquires_total", "The total number of successful acquires from the pool."),
+		acquireDuration:      desc("pool_acquire_duration_seconds_total", "The total duration of all successful acquires from the pool."),
+		emptyAcquireCount:    desc("pool_empty_acquires_total", "The total number of successful acquires that waited for a connection because the pool was empty."),
+		canceledAcquireCount: desc("pool_canceled_acquires_total", "The total number of acquires canceled by a context."),
+	}
+
+	// Collectors of different pools can't be shared, hence AlreadyRegisteredError is returned as is.
+	if err := config.Registerer.Register(c); err != nil {
+		return nil, err
+	}
+
+	return c, nil
+}
+
+// Describe implements prometheus.Collector.
+func (c *poolCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.totalConns
+	ch <- c.idleConns
+	ch <- c.acquiredConns
+	ch <- c.constructingConns
+	ch <- c.maxConns
+	ch <- c.acquireCount
+	ch <- c.acquireDuration
+	ch <- c.emptyAcquireCount
+	ch <- c.canceledAcquireCount
+}
+
+// Collect implements prometheus.Collector.
+func (c *poolCollector) Collect(ch chan<- prometheus.Metric) {
+	stat := c.pool.Stat()
+
+	ch <- prometheus.MustNewConstMetric(c.totalConns, prometheus.GaugeValue, float64(stat.TotalConns()))
+	ch <- prometheus.MustNewConstMetric(c.idleConns, prometheus.GaugeValue, float64(stat.IdleConns()))
+	ch <- prometheus.MustNewConstMetric(c.acquiredConns, prometheus.GaugeValue, float64(stat.AcquiredConns()))
+	ch <- prometheus.MustNewConstMetric(c.constructingConns, prometheus.GaugeValue, float64(stat.ConstructingConns()))
+	ch <- prometheus.MustNewConstMetric(c.maxConns, prometheus.GaugeValue, float64(stat.MaxConns()))
+	ch <- prometheus.MustNewConstMetric(c.acquireCount, prometheus.CounterValue, float64(stat.AcquireCount()))
+	ch <- prometheus.MustNewConstMetric(c.acquireDuration, prometheus.CounterValue, stat.AcquireDuration().Seconds())
+	ch <- prometheus.MustNewConstMetric(c.emptyAcquireCount, prometheus.CounterValue, float64(stat.EmptyAcquireCount()))
+	ch <- prometheus.MustNewConstMetric(c.canceledAcquireCount, prometheus.CounterValue, float64(stat.CanceledAcquireCount()))
+}
@@ -0,0 +1,57 @@
+package postgres_test
+
+import (
+	"context"
+	"github.com/jackc/pgx/v4/pgxpool"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/weaponry/go-instrumenting/metrics"
+	postgresmetrics "github.com/weaponry/go-instrumenting/metrics/postgres"
+	"io/ioutil"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestRegisterPoolCollector(t *testing.T) {
+	poolConfig, err := pgxpool.ParseConfig("postgres://user@127.0.0.1:1/db?pool_max_conns=7")
+	require.NoError(t, err)
+	poolConfig.LazyConnect = true
+
+	pool, err := pgxpool.ConnectConfig(context.Background(), poolConfig)
+	require.NoError(t, err)
+	defer pool.Close()
+
+	registry := prometheus.NewRegistry()
+	config := postgresmetrics.Config{
+		Options: metrics.Options{ConstLabels: prometheus.Labels{"pool": "primary"}, Registerer: registry},
+	}
+
+	_, err = postgresmetrics.RegisterPoolCollector("test-app", pool, config)
+	require.NoError(t, err)
+
+	// Collector of another pool with the same labels should not be registered.
+	_, err = postgresmetrics.RegisterPoolCollector("test-app", pool, config)
+	assert.Error(t, err)
+
+	// Get the metrics handler and serve.
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest("GET", "/metrics", nil)
+	promhttp.HandlerFor(registry, promhttp.HandlerOpts{}).ServeHTTP(rec, req)
+
+	body, _ := ioutil.ReadAll(rec.Result().Body)
+	for _, expMetric := range []string{
+		`app_postgres_pool_max_connections{application="test-app",pool="primary"} 7`,
+		`app_postgres_pool_total_connections{application="test-app",pool="primary"} 0`,
+		`app_postgres_pool_idle_connections{application="test-app",pool="primary"} 0`,
+		`app_postgres_pool_acquired_connections{application="test-app",pool="primary"} 0`,
+		`app_postgres_pool_constructing_connections{application="test-app",pool="primary"} 0`,
+		`app_postgres_pool_acquires_total{application="test-app",pool="primary"} 0`,
+		`app_postgres_pool_acquire_duration_seconds_total{application="test-app",pool="primary"} 0`,
+		`app_postgres_pool_empty_acquires_total{application="test-app",pool="primary"} 0`,
+		`app_postgres_pool_canceled_acquires_total{application="test-app",pool="primary"} 0`,
+	} {
+		assert.Contains(t, string(body), expMetric, "metric not present on the result")
+	}
+}
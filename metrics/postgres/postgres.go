@@ -4,36 +4,163 @@ import (
 	"github.com/jackc/pgx/v4"
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/weaponry/go-instrumenting/metrics"
+	"time"
 )
 
 const (
-	labelApp = "application"
+	labelQuery     = "query"
+	labelOutcome   = "outcome"
+	labelIsolation = "isolation"
 )
 
+type Config struct {
+	// Options describe names of metrics, constant labels and the registerer, by default metrics are named as
+	// 'app_<subsystem>_*' and registered with prometheus.DefaultRegisterer.
+	metrics.Options
+	// DurationBuckets are the buckets used by Prometheus for the query duration metrics,
+	// by default uses Prometheus default buckets (from 5ms to 10s).
+	DurationBuckets []float64
+	// RowsBuckets are the buckets used by Prometheus for the number of rows returned or affected by queries,
+	// by default uses a exponential buckets from 1 to 16384.
+	RowsBuckets []float64
+	// StatementsBuckets are the buckets used by Prometheus for the number of statements executed by transactions,
+	// by default uses a exponential buckets from 1 to 512.
+	StatementsBuckets []float64
+}
+
+func (c *Config) defaults() {
+	c.SetDefaults("postgres")
+
+	if len(c.DurationBuckets) == 0 {
+		c.DurationBuckets = prometheus.DefBuckets
+	}
+
+	if len(c.RowsBuckets) == 0 {
+		c.RowsBuckets = prometheus.ExponentialBuckets(1, 4, 8)
+	}
+
+	if len(c.StatementsBuckets) == 0 {
+		c.StatementsBuckets = prometheus.ExponentialBuckets(1, 2, 10)
+	}
+}
+
 type recorder struct {
-	Registry      prometheus.Registerer
-	RequestsTotal *prometheus.CounterVec
+	Registry               prometheus.Registerer
+	gatherer               prometheus.Gatherer
+	RequestsTotal          *prometheus.CounterVec
+	QueriesTotal           *prometheus.CounterVec
+	QueryDurationHistogram *prometheus.HistogramVec
+	QueryRowsHistogram     *prometheus.HistogramVec
+	TxTotal                *prometheus.CounterVec
+	TxDurationHistogram    *prometheus.HistogramVec
+	TxStatementsHistogram  *prometheus.HistogramVec
 }
 
+// NewPostgresRecorder creates Postgres recorder with the default configuration and registers its metrics, it panics
+// if registration fails.
 func NewPostgresRecorder(appName string) metrics.PostgresRecorder {
+	return NewPostgresRecorderWithConfig(appName, Config{})
+}
+
+// NewPostgresRecorderWithConfig creates Postgres recorder and registers its metrics, it panics if registration fails.
+func NewPostgresRecorderWithConfig(appName string, config Config) metrics.PostgresRecorder {
+	r, err := RegisterPostgresRecorder(appName, config)
+	if err != nil {
+		panic(err)
+	}
+	return r
+}
+
+// RegisterPostgresRecorder creates Postgres recorder and registers its metrics. Metrics which have been already
+// registered by another recorder with the same configuration are reused.
+func RegisterPostgresRecorder(appName string, config Config) (metrics.PostgresRecorder, error) {
+	config.defaults()
+
+	if err := config.Validate(labelQuery, labelOutcome, labelIsolation); err != nil {
+		return nil, err
+	}
+
+	constLabels := config.Labels(appName)
 
 	r := &recorder{
 		RequestsTotal: prometheus.NewCounterVec(prometheus.CounterOpts{
-			Namespace:   "app",
-			Subsystem:   "postgres",
+			Namespace:   config.Namespace,
+			Subsystem:   config.Subsystem,
 			Name:        "xacts_total",
 			Help:        "The total number of processed transactions.",
-			ConstLabels: map[string]string{labelApp: appName},
+			ConstLabels: constLabels,
 		}, []string{}),
+
+		QueriesTotal: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace:   config.Namespace,
+			Subsystem:   config.Subsystem,
+			Name:        "queries_total",
+			Help:        "The total number of executed queries.",
+			ConstLabels: constLabels,
+		}, []string{labelQuery, labelOutcome}),
+
+		QueryDurationHistogram: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace:   config.Namespace,
+			Subsystem:   config.Subsystem,
+			Name:        "query_duration_seconds",
+			Help:        "The latency of the queries.",
+			Buckets:     config.DurationBuckets,
+			ConstLabels: constLabels,
+		}, []string{labelQuery, labelOutcome}),
+
+		QueryRowsHistogram: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace:   config.Namespace,
+			Subsystem:   config.Subsystem,
+			Name:        "query_rows",
+			Help:        "The number of rows returned or affected by the successful queries.",
+			Buckets:     config.RowsBuckets,
+			ConstLabels: constLabels,
+		}, []string{labelQuery}),
+
+		TxTotal: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace:   config.Namespace,
+			Subsystem:   config.Subsystem,
+			Name:        "transactions_total",
+			Help:        "The total number of finished transactions.",
+			ConstLabels: constLabels,
+		}, []string{labelIsolation, labelOutcome}),
+
+		TxDurationHistogram: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace:   config.Namespace,
+			Subsystem:   config.Subsystem,
+			Name:        "transaction_duration_seconds",
+			Help:        "The duration of the transactions from begin to commit or rollback.",
+			Buckets:     config.DurationBuckets,
+			ConstLabels: constLabels,
+		}, []string{labelIsolation, labelOutcome}),
+
+		TxStatementsHistogram: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace:   config.Namespace,
+			Subsystem:   config.Subsystem,
+			Name:        "transaction_statements",
+			Help:        "The number of statements executed by the transactions.",
+			Buckets:     config.StatementsBuckets,
+			ConstLabels: constLabels,
+		}, []string{labelIsolation, labelOutcome}),
 	}
 
-	r.Registry = prometheus.DefaultRegisterer
+	r.Registry = config.Registerer
+	r.gatherer = config.Gatherer
 
-	r.Registry.MustRegister(
-		r.RequestsTotal,
+	err := metrics.Register(r.Registry,
+		&r.RequestsTotal,
+		&r.QueriesTotal,
+		metrics.Histogram(&r.QueryDurationHistogram, config.DurationBuckets),
+		metrics.Histogram(&r.QueryRowsHistogram, config.RowsBuckets),
+		&r.TxTotal,
+		metrics.Histogram(&r.TxDurationHistogram, config.DurationBuckets),
+		metrics.Histogram(&r.TxStatementsHistogram, config.StatementsBuckets),
 	)
+	if err != nil {
+		return nil, err
+	}
 
-	return r
+	return r, nil
 }
 
 // Collect updates metrics using passed properties
@@ -41,9 +168,42 @@ func (r recorder) Collect() {
 	r.RequestsTotal.WithLabelValues().Inc()
 }
 
+// CollectQuery updates query metrics using passed properties, negative duration means it is unknown
+func (r recorder) CollectQuery(props metrics.PostgresQueryProperties, duration time.Duration, rows int64) {
+	r.QueriesTotal.WithLabelValues(props.Query, props.Outcome).Inc()
+
+	if duration >= 0 {
+		r.QueryDurationHistogram.WithLabelValues(props.Query, props.Outcome).Observe(duration.Seconds())
+	}
+
+	if props.Outcome == outcomeOK {
+		r.QueryRowsHistogram.WithLabelValues(props.Query).Observe(float64(rows))
+	}
+}
+
+// CollectTx updates transaction metrics using passed properties
+func (r recorder) CollectTx(props metrics.PostgresTxProperties, duration time.Duration, statements int) {
+	r.TxTotal.WithLabelValues(props.Isolation, props.Outcome).Inc()
+	r.TxDurationHistogram.WithLabelValues(props.Isolation, props.Outcome).Observe(duration.Seconds())
+	r.TxStatementsHistogram.WithLabelValues(props.Isolation, props.Outcome).Observe(float64(statements))
+}
+
+// Gatherer returns the gatherer used for gathering recorded metrics.
+func (r recorder) Gatherer() prometheus.Gatherer {
+	return r.gatherer
+}
+
 // Unregister ...
 func (r recorder) Unregister() {
-	r.Registry.Unregister(r.RequestsTotal)
+	metrics.Unregister(r.Registry,
+		r.RequestsTotal,
+		r.QueriesTotal,
+		r.QueryDurationHistogram,
+		r.QueryRowsHistogram,
+		r.TxTotal,
+		r.TxDurationHistogram,
+		r.TxStatementsHistogram,
+	)
 }
 
 func (r recorder) AfterReleaseHook(_ *pgx.Conn) bool {
@@ -4,6 +4,7 @@ import (
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 	"github.com/weaponry/go-instrumenting/metrics"
 	postgresmetrics "github.com/weaponry/go-instrumenting/metrics/postgres"
 	"io/ioutil"
@@ -61,3 +62,28 @@ func TestNewPostgresRecorder(t *testing.T) {
 		})
 	}
 }
+
+func TestRegisterPostgresRecorder(t *testing.T) {
+	registry := prometheus.NewRegistry()
+
+	// Recorders with the same configuration should share metrics.
+	first, err := postgresmetrics.RegisterPostgresRecorder("test-app", postgresmetrics.Config{Options: metrics.Options{Registerer: registry}})
+	require.NoError(t, err)
+	second, err := postgresmetrics.RegisterPostgresRecorder("test-app", postgresmetrics.Config{Options: metrics.Options{Registerer: registry}})
+	require.NoError(t, err)
+
+	first.Collect()
+	second.Collect()
+
+	// Get the metrics handler and serve.
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest("GET", "/metrics", nil)
+	promhttp.HandlerFor(registry, promhttp.HandlerOpts{}).ServeHTTP(rec, req)
+
+	body, _ := ioutil.ReadAll(rec.Result().Body)
+	assert.Contains(t, string(body), `app_postgres_xacts_total{application="test-app"} 2`)
+
+	// Recorders with different applications should not clash.
+	_, err = postgresmetrics.RegisterPostgresRecorder("another-app", postgresmetrics.Config{Options: metrics.Options{Registerer: registry}})
+	assert.NoError(t, err)
+}
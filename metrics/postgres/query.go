@@ -0,0 +1,287 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/jackc/pgconn"
+	"github.com/jackc/pgx/v4"
+	"github.com/weaponry/go-instrumenting/metrics"
+	"hash/fnv"
+	"regexp"
+	"strings"
+	"time"
+)
+
+const (
+	outcomeOK       = "ok"
+	outcomeError    = "error"
+	outcomeCanceled = "canceled"
+
+	// pgCodeQueryCanceled is an error code reported when query is canceled by user or by statement timeout.
+	pgCodeQueryCanceled = "57014"
+
+	keyQueryName key = iota
+)
+
+type key int
+
+// WithQueryName returns a copy of ctx carrying name of the query, which is used as a query label instead of
+// fingerprint of the query.
+func WithQueryName(ctx context.Context, name string) context.Context {
+	return context.WithValue(ctx, keyQueryName, name)
+}
+
+// queryName returns name of the query passed via context, or fingerprint of the query.
+func queryName(ctx context.Context, sql string) string {
+	if name, ok := ctx.Value(keyQueryName).(string); ok && name != "" {
+		return name
+	}
+	return Fingerprint(sql)
+}
+
+// queryOutcome returns outcome of the query finished with passed error.
+func queryOutcome(err error) string {
+	if err == nil || errors.Is(err, pgx.ErrNoRows) {
+		return outcomeOK
+	}
+
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return outcomeCanceled
+	}
+
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == pgCodeQueryCanceled {
+		return outcomeCanceled
+	}
+
+	return outcomeError
+}
+
+/*
+ * Query fingerprints
+ */
+
+var (
+	reComments   = regexp.MustCompile(`(?s)--[^\n]*|/\*.*?\*/`)
+	reStrings    = regexp.MustCompile(`'(?:[^']|'')*'`)
+	reParams     = regexp.MustCompile(`\$\d+`)
+	reNumbers    = regexp.MustCompile(`\b\d+(?:\.\d+)?\b`)
+	reLists      = regexp.MustCompile(`\(\s*\?(?:\s*,\s*\?)*\s*\)`)
+	reWhitespace = regexp.MustCompile(`\s+`)
+)
+
+// Fingerprint returns a short label identifying the query regardless of its literals and formatting. It consists of
+// the statement keyword and a hash of the normalized query, e.g. 'select_1a2b3c4d'.
+func Fingerprint(sql string) string {
+	normalized := reComments.ReplaceAllString(sql, " ")
+	normalized = reStrings.ReplaceAllString(normalized, "?")
+	normalized = reParams.ReplaceAllString(normalized, "?")
+	normalized = reNumbers.ReplaceAllString(normalized, "?")
+	normalized = reLists.ReplaceAllString(normalized, "(?)")
+	normalized = strings.ToLower(strings.TrimSpace(reWhitespace.ReplaceAllString(normalized, " ")))
+
+	keyword := normalized
+	if i := strings.IndexAny(keyword, " ("); i >= 0 {
+		keyword = keyword[:i]
+	}
+	if keyword == "" {
+		keyword = "query"
+	}
+
+	h := fnv.New32a()
+	_, _ = h.Write([]byte(normalized))
+
+	return fmt.Sprintf("%s_%08x", keyword, h.Sum32())
+}
+
+/*
+ * pgx.Logger adapter
+ */
+
+// queryLogger records metrics of queries logged by pgx and passes all messages to the next logger.
+type queryLogger struct {
+	recorder metrics.PostgresQueryRecorder
+	next     pgx.Logger
+}
+
+// NewQueryLogger returns pgx.Logger which records metrics of queries logged by pgx connections. All messages are
+// passed to next logger if it is not nil. Successful queries are logged only if LogLevel of connection is
+// pgx.LogLevelInfo or higher, duration is not logged by pgx for failed queries.
+func (r recorder) NewQueryLogger(next pgx.Logger) pgx.Logger {
+	return NewQueryLogger(next, r)
+}
+
+// NewQueryLogger returns pgx.Logger which passes metrics of queries logged by pgx connections to recorder and all
+// messages to next logger if it is not nil. Next logger is returned as is if recorder doesn't implement
+// metrics.PostgresQueryRecorder.
+func NewQueryLogger(next pgx.Logger, recorder metrics.PostgresRecorder) pgx.Logger {
+	queries, ok := recorder.(metrics.PostgresQueryRecorder)
+	if !ok {
+		return next
+	}
+	return &queryLogger{recorder: queries, next: next}
+}
+
+func (l *queryLogger) Log(ctx context.Context, level pgx.LogLevel, msg string, data map[string]interface{}) {
+	if msg == "Query" || msg == "Exec" {
+		sql, _ := data["sql"].(string)
+		err, _ := data["err"].(error)
+
+		duration := time.Duration(-1)
+		if d, ok := data["time"].(time.Duration); ok {
+			duration = d
+		}
+
+		var rows int64
+		if n, ok := data["rowCount"].(int); ok {
+			rows = int64(n)
+		}
+		if tag, ok := data["commandTag"].(pgconn.CommandTag); ok {
+			rows = tag.RowsAffected()
+		}
+
+		props := metrics.PostgresQueryProperties{
+			Query:   queryName(ctx, sql),
+			Outcome: queryOutcome(err),
+		}
+		l.recorder.CollectQuery(props, duration, rows)
+	}
+
+	if l.next != nil {
+		l.next.Log(ctx, level, msg, data)
+	}
+}
+
+/*
+ * Querier wrapper
+ */
+
+// Querier is the interface implemented by *pgxpool.Pool, *pgx.Conn and pgx.Tx for executing queries.
+type Querier interface {
+	Exec(ctx context.Context, sql string, arguments ...interface{}) (pgconn.CommandTag, error)
+	Query(ctx context.Context, sql string, args ...interface{}) (pgx.Rows, error)
+	QueryRow(ctx context.Context, sql string, args ...interface{}) pgx.Row
+}
+
+// instrumentedQuerier records metrics of queries executed by wrapped Querier.
+type instrumentedQuerier struct {
+	querier  Querier
+	recorder metrics.PostgresQueryRecorder
+}
+
+// NewQuerier returns Querier which records metrics of queries executed with q. Metrics of Query are recorded
+// when returned rows are closed or read till the end, metrics of QueryRow are recorded when the row is scanned.
+// Querier q is returned as is if recorder doesn't implement metrics.PostgresQueryRecorder.
+func NewQuerier(q Querier, recorder metrics.PostgresRecorder) Querier {
+	queries, ok := recorder.(metrics.PostgresQueryRecorder)
+	if !ok {
+		return q
+	}
+	return &instrumentedQuerier{querier: q, recorder: queries}
+}
+
+func (q *instrumentedQuerier) Exec(ctx context.Context, sql string, arguments ...interface{}) (pgconn.CommandTag, error) {
+	start := time.Now()
+	tag, err := q.querier.Exec(ctx, sql, arguments...)
+
+	props := metrics.PostgresQueryProperties{
+		Query:   queryName(ctx, sql),
+		Outcome: queryOutcome(err),
+	}
+	q.recorder.CollectQuery(props, time.Since(start), tag.RowsAffected())
+
+	return tag, err
+}
+
+func (q *instrumentedQuerier) Query(ctx context.Context, sql string, args ...interface{}) (pgx.Rows, error) {
+	start := time.Now()
+	rows, err := q.querier.Query(ctx, sql, args...)
+	if err != nil {
+		props := metrics.PostgresQueryProperties{
+			Query:   queryName(ctx, sql),
+			Outcome: queryOutcome(err),
+		}
+		q.recorder.CollectQuery(props, time.Since(start), 0)
+
+		return rows, err
+	}
+
+	return &instrumentedRows{Rows: rows, recorder: q.recorder, query: queryName(ctx, sql), start: start}, nil
+}
+
+func (q *instrumentedQuerier) QueryRow(ctx context.Context, sql string, args ...interface{}) pgx.Row {
+	start := time.Now()
+	row := q.querier.QueryRow(ctx, sql, args...)
+
+	return &instrumentedRow{row: row, recorder: q.recorder, query: queryName(ctx, sql), start: start}
+}
+
+// instrumentedRows records metrics of the query when rows are closed.
+type instrumentedRows struct {
+	pgx.Rows
+	recorder metrics.PostgresQueryRecorder
+	query    string
+	start    time.Time
+	count    int64
+	done     bool
+}
+
+func (r *instrumentedRows) Next() bool {
+	if r.Rows.Next() {
+		r.count++
+		return true
+	}
+
+	// Rows are closed automatically when all rows are read.
+	r.finish()
+	return false
+}
+
+func (r *instrumentedRows) Close() {
+	r.Rows.Close()
+	r.finish()
+}
+
+func (r *instrumentedRows) finish() {
+	if r.done {
+		return
+	}
+	r.done = true
+
+	rows := r.count
+	if affected := r.Rows.CommandTag().RowsAffected(); affected > rows {
+		rows = affected
+	}
+
+	props := metrics.PostgresQueryProperties{
+		Query:   r.query,
+		Outcome: queryOutcome(r.Rows.Err()),
+	}
+	r.recorder.CollectQuery(props, time.Since(r.start), rows)
+}
+
+// instrumentedRow records metrics of the query when row is scanned.
+type instrumentedRow struct {
+	row      pgx.Row
+	recorder metrics.PostgresQueryRecorder
+	query    string
+	start    time.Time
+}
+
+func (r *instrumentedRow) Scan(dest ...interface{}) error {
+	err := r.row.Scan(dest...)
+
+	var rows int64
+	if err == nil {
+		rows = 1
+	}
+
+	props := metrics.PostgresQueryProperties{
+		Query:   r.query,
+		Outcome: queryOutcome(err),
+	}
+	r.recorder.CollectQuery(props, time.Since(r.start), rows)
+
+	return err
+}
@@ -0,0 +1,175 @@
+package postgres_test
+
+import (
+	"context"
+	"errors"
+	"github.com/jackc/pgconn"
+	"github.com/jackc/pgx/v4"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/weaponry/go-instrumenting/metrics"
+	postgresmetrics "github.com/weaponry/go-instrumenting/metrics/postgres"
+	"io/ioutil"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// fakeQuerier returns predefined results for all queries.
+type fakeQuerier struct {
+	tag  pgconn.CommandTag
+	rows int
+	err  error
+}
+
+func (q *fakeQuerier) Exec(context.Context, string, ...interface{}) (pgconn.CommandTag, error) {
+	return q.tag, q.err
+}
+
+func (q *fakeQuerier) Query(context.Context, string, ...interface{}) (pgx.Rows, error) {
+	return &fakeRows{left: q.rows, tag: q.tag, err: q.err}, nil
+}
+
+func (q *fakeQuerier) QueryRow(context.Context, string, ...interface{}) pgx.Row {
+	return &fakeRows{left: q.rows, tag: q.tag, err: q.err}
+}
+
+// fakeRows implements used methods of pgx.Rows.
+type fakeRows struct {
+	pgx.Rows
+	left int
+	tag  pgconn.CommandTag
+	err  error
+}
+
+func (r *fakeRows) Next() bool {
+	if r.left == 0 || r.err != nil {
+		return false
+	}
+	r.left--
+	return true
+}
+
+func (r *fakeRows) Scan(...interface{}) error {
+	if r.err != nil {
+		return r.err
+	}
+	if r.left == 0 {
+		return pgx.ErrNoRows
+	}
+	return nil
+}
+
+func (r *fakeRows) Close()                        {}
+func (r *fakeRows) Err() error                    { return r.err }
+func (r *fakeRows) CommandTag() pgconn.CommandTag { return r.tag }
+
+func TestFingerprint(t *testing.T) {
+	a := postgresmetrics.Fingerprint("SELECT id, name FROM users WHERE id = $1 AND status IN ('a', 'b')")
+	b := postgresmetrics.Fingerprint("select id, name\n  from users -- by id\n where id = 42 and status in ('c')")
+	c := postgresmetrics.Fingerprint("SELECT id FROM users")
+
+	assert.Equal(t, a, b, "queries differing only in literals and formatting should have the same fingerprint")
+	assert.NotEqual(t, a, c)
+	assert.Regexp(t, `^select_[0-9a-f]{8}$`, a)
+	assert.Regexp(t, `^insert_[0-9a-f]{8}$`, postgresmetrics.Fingerprint("INSERT INTO users(name) VALUES ('x')"))
+}
+
+func TestNewQuerier(t *testing.T) {
+	registry := prometheus.NewRegistry()
+	recorder, err := postgresmetrics.RegisterPostgresRecorder("test-app", postgresmetrics.Config{Options: metrics.Options{Registerer: registry}})
+	require.NoError(t, err)
+
+	ctx := postgresmetrics.WithQueryName(context.Background(), "update-users")
+	_, _ = postgresmetrics.NewQuerier(&fakeQuerier{tag: pgconn.CommandTag("UPDATE 3")}, recorder).Exec(ctx, "UPDATE users SET a = 1")
+
+	ctx = postgresmetrics.WithQueryName(context.Background(), "list-users")
+	rows, err := postgresmetrics.NewQuerier(&fakeQuerier{tag: pgconn.CommandTag("SELECT 5"), rows: 5}, recorder).Query(ctx, "SELECT * FROM users")
+	require.NoError(t, err)
+	for rows.Next() {
+	}
+	rows.Close()
+
+	ctx = postgresmetrics.WithQueryName(context.Background(), "get-user")
+	var id int
+	_ = postgresmetrics.NewQuerier(&fakeQuerier{rows: 0}, recorder).QueryRow(ctx, "SELECT id FROM users").Scan(&id)
+	_ = postgresmetrics.NewQuerier(&fakeQuerier{err: context.Canceled}, recorder).QueryRow(ctx, "SELECT id FROM users").Scan(&id)
+	_ = postgresmetrics.NewQuerier(&fakeQuerier{err: &pgconn.PgError{Code: "57014"}}, recorder).QueryRow(ctx, "SELECT id FROM users").Scan(&id)
+	_ = postgresmetrics.NewQuerier(&fakeQuerier{err: errors.New("conn closed")}, recorder).QueryRow(ctx, "SELECT id FROM users").Scan(&id)
+
+	// Get the metrics handler and serve.
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest("GET", "/metrics", nil)
+	promhttp.HandlerFor(registry, promhttp.HandlerOpts{}).ServeHTTP(rec, req)
+
+	body, _ := ioutil.ReadAll(rec.Result().Body)
+	for _, expMetric := range []string{
+		`app_postgres_queries_total{application="test-app",outcome="ok",query="update-users"} 1`,
+		`app_postgres_query_rows_sum{application="test-app",query="update-users"} 3`,
+		`app_postgres_queries_total{application="test-app",outcome="ok",query="list-users"} 1`,
+		`app_postgres_query_rows_sum{application="test-app",query="list-users"} 5`,
+		`app_postgres_queries_total{application="test-app",outcome="ok",query="get-user"} 1`,
+		`app_postgres_queries_total{application="test-app",outcome="canceled",query="get-user"} 2`,
+		`app_postgres_queries_total{application="test-app",outcome="error",query="get-user"} 1`,
+		`app_postgres_query_duration_seconds_count{application="test-app",outcome="canceled",query="get-user"} 2`,
+	} {
+		assert.Contains(t, string(body), expMetric, "metric not present on the result")
+	}
+}
+
+// countingLogger counts logged messages.
+type countingLogger struct {
+	count int
+}
+
+func (l *countingLogger) Log(context.Context, pgx.LogLevel, string, map[string]interface{}) {
+	l.count++
+}
+
+func TestNewQueryLogger(t *testing.T) {
+	registry := prometheus.NewRegistry()
+	recorder, err := postgresmetrics.RegisterPostgresRecorder("test-app", postgresmetrics.Config{Options: metrics.Options{Registerer: registry}})
+	require.NoError(t, err)
+
+	next := &countingLogger{}
+	logger := postgresmetrics.NewQueryLogger(next, recorder)
+
+	sql := "SELECT * FROM users WHERE id = $1"
+	logger.Log(context.Background(), pgx.LogLevelInfo, "Query", map[string]interface{}{"sql": sql, "time": 20 * time.Millisecond, "rowCount": 1})
+	logger.Log(context.Background(), pgx.LogLevelError, "Exec", map[string]interface{}{"sql": sql, "err": errors.New("syntax error")})
+	logger.Log(context.Background(), pgx.LogLevelInfo, "closed connection", nil)
+
+	assert.Equal(t, 3, next.count, "all messages should be passed to the next logger")
+
+	// Get the metrics handler and serve.
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest("GET", "/metrics", nil)
+	promhttp.HandlerFor(registry, promhttp.HandlerOpts{}).ServeHTTP(rec, req)
+
+	body, _ := ioutil.ReadAll(rec.Result().Body)
+	fingerprint := postgresmetrics.Fingerprint(sql)
+	assert.Contains(t, string(body), `app_postgres_queries_total{application="test-app",outcome="ok",query="`+fingerprint+`"} 1`)
+	assert.Contains(t, string(body), `app_postgres_queries_total{application="test-app",outcome="error",query="`+fingerprint+`"} 1`)
+	assert.Contains(t, string(body), `app_postgres_query_duration_seconds_count{application="test-app",outcome="ok",query="`+fingerprint+`"} 1`)
+	assert.NotContains(t, string(body), `app_postgres_query_duration_seconds_count{application="test-app",outcome="error"`)
+}
+
+// basePostgresRecorder implements only metrics.PostgresRecorder, as recorders created by other packages may do.
+type basePostgresRecorder struct{}
+
+func (basePostgresRecorder) AfterReleaseHook(*pgx.Conn) bool { return true }
+
+func (basePostgresRecorder) Collect() {}
+
+func (basePostgresRecorder) Unregister() {}
+
+func TestQueriesBaseRecorder(t *testing.T) {
+	// Queries should not be wrapped if recorder doesn't record them.
+	querier := &fakeQuerier{}
+	assert.Same(t, querier, postgresmetrics.NewQuerier(querier, basePostgresRecorder{}))
+
+	next := &countingLogger{}
+	assert.Same(t, next, postgresmetrics.NewQueryLogger(next, basePostgresRecorder{}))
+}
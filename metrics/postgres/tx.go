@@ -0,0 +1,277 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"github.com/jackc/pgconn"
+	"github.com/jackc/pgx/v4"
+	"github.com/weaponry/go-instrumenting/metrics"
+	"strings"
+	"time"
+)
+
+const (
+	outcomeCommit               = "commit"
+	outcomeRollback             = "rollback"
+	outcomeCommitFailed         = "commit_failed"
+	outcomeSerializationFailure = "serialization_failure"
+
+	// isolationDefault is a value of the isolation label used when isolation level is not set by transaction options.
+	isolationDefault = "default"
+
+	// pgCodeSerializationFailure is an error code reported when transaction can't be serialized with concurrent ones.
+	pgCodeSerializationFailure = "40001"
+)
+
+// TxBeginner is the interface implemented by *pgxpool.Pool and *pgx.Conn for beginning transactions.
+type TxBeginner interface {
+	Begin(ctx context.Context) (pgx.Tx, error)
+	BeginTx(ctx context.Context, txOptions pgx.TxOptions) (pgx.Tx, error)
+}
+
+// instrumentedBeginner records metrics of transactions begun by wrapped TxBeginner.
+type instrumentedBeginner struct {
+	beginner TxBeginner
+	recorder metrics.PostgresTxRecorder
+}
+
+// NewTxBeginner returns TxBeginner which records metrics of transactions begun with b. Metrics are recorded by the
+// first Commit or Rollback of the transaction. Statements executed by pseudo nested transactions (savepoints) are
+// counted by the top level transaction. Beginner b is returned as is if recorder doesn't implement
+// metrics.PostgresTxRecorder.
+func NewTxBeginner(b TxBeginner, recorder metrics.PostgresRecorder) TxBeginner {
+	txs, ok := recorder.(metrics.PostgresTxRecorder)
+	if !ok {
+		return b
+	}
+	return &instrumentedBeginner{beginner: b, recorder: txs}
+}
+
+func (b *instrumentedBeginner) Begin(ctx context.Context) (pgx.Tx, error) {
+	start := time.Now()
+	tx, err := b.beginner.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return newInstrumentedTx(tx, "", b.recorder, start), nil
+}
+
+func (b *instrumentedBeginner) BeginTx(ctx context.Context, txOptions pgx.TxOptions) (pgx.Tx, error) {
+	start := time.Now()
+	tx, err := b.beginner.BeginTx(ctx, txOptions)
+	if err != nil {
+		return nil, err
+	}
+
+	return newInstrumentedTx(tx, txOptions.IsoLevel, b.recorder, start), nil
+}
+
+// WrapTx returns transaction which records metrics of already begun tx like transactions begun by NewTxBeginner,
+// isoLevel is the isolation level tx has been begun with (empty for the default one). Duration of the transaction is
+// measured since wrapping. Transaction tx is returned as is if recorder doesn't implement metrics.PostgresTxRecorder.
+func WrapTx(tx pgx.Tx, isoLevel pgx.TxIsoLevel, recorder metrics.PostgresRecorder) pgx.Tx {
+	txs, ok := recorder.(metrics.PostgresTxRecorder)
+	if !ok {
+		return tx
+	}
+	return newInstrumentedTx(tx, isoLevel, txs, time.Now())
+}
+
+// isolationLevel returns value of the isolation label, e.g. 'repeatable_read'.
+func isolationLevel(level pgx.TxIsoLevel) string {
+	if level == "" {
+		return isolationDefault
+	}
+	return strings.ReplaceAll(strings.ToLower(string(level)), " ", "_")
+}
+
+// isSerializationFailure reports whether err is caused by a serialization failure.
+func isSerializationFailure(err error) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == pgCodeSerializationFailure
+}
+
+// instrumentedTx counts statements of the transaction and records its metrics when it is finished.
+type instrumentedTx struct {
+	pgx.Tx
+	// root is the top level transaction, it is the transaction itself unless it is a pseudo nested one.
+	root      *instrumentedTx
+	recorder  metrics.PostgresTxRecorder
+	isolation string
+	start     time.Time
+
+	statements           int
+	serializationFailure bool
+	done                 bool
+}
+
+// newInstrumentedTx returns top level transaction begun at start.
+func newInstrumentedTx(tx pgx.Tx, isoLevel pgx.TxIsoLevel, recorder metrics.PostgresTxRecorder, start time.Time) *instrumentedTx {
+	t := &instrumentedTx{Tx: tx, recorder: recorder, isolation: isolationLevel(isoLevel), start: start}
+	t.root = t
+	return t
+}
+
+// observe counts statements executed by the transaction and checks their error.
+func (t *instrumentedTx) observe(statements int, err error) {
+	t.root.statements += statements
+	if isSerializationFailure(err) {
+		t.root.serializationFailure = true
+	}
+}
+
+// finish records metrics of the top level transaction unless they have been already recorded.
+func (t *instrumentedTx) finish(outcome string) {
+	if t.root != t || t.done {
+		return
+	}
+	t.done = true
+
+	if t.serializationFailure {
+		outcome = outcomeSerializationFailure
+	}
+
+	props := metrics.PostgresTxProperties{
+		Isolation: t.isolation,
+		Outcome:   outcome,
+	}
+	t.recorder.CollectTx(props, time.Since(t.start), t.statements)
+}
+
+func (t *instrumentedTx) Begin(ctx context.Context) (pgx.Tx, error) {
+	tx, err := t.Tx.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return &instrumentedTx{Tx: tx, root: t.root}, nil
+}
+
+func (t *instrumentedTx) Commit(ctx context.Context) error {
+	err := t.Tx.Commit(ctx)
+	switch {
+	case errors.Is(err, pgx.ErrTxClosed):
+	case err == nil:
+		t.finish(outcomeCommit)
+	default:
+		t.observe(0, err)
+		t.finish(outcomeCommitFailed)
+	}
+
+	return err
+}
+
+func (t *instrumentedTx) Rollback(ctx context.Context) error {
+	err := t.Tx.Rollback(ctx)
+	if !errors.Is(err, pgx.ErrTxClosed) {
+		t.finish(outcomeRollback)
+	}
+
+	return err
+}
+
+func (t *instrumentedTx) CopyFrom(ctx context.Context, tableName pgx.Identifier, columnNames []string, rowSrc pgx.CopyFromSource) (int64, error) {
+	n, err := t.Tx.CopyFrom(ctx, tableName, columnNames, rowSrc)
+	t.observe(1, err)
+
+	return n, err
+}
+
+func (t *instrumentedTx) SendBatch(ctx context.Context, b *pgx.Batch) pgx.BatchResults {
+	results := t.Tx.SendBatch(ctx, b)
+	t.observe(b.Len(), nil)
+
+	return &instrumentedBatchResults{BatchResults: results, tx: t}
+}
+
+func (t *instrumentedTx) Exec(ctx context.Context, sql string, arguments ...interface{}) (pgconn.CommandTag, error) {
+	tag, err := t.Tx.Exec(ctx, sql, arguments...)
+	t.observe(1, err)
+
+	return tag, err
+}
+
+func (t *instrumentedTx) Query(ctx context.Context, sql string, args ...interface{}) (pgx.Rows, error) {
+	rows, err := t.Tx.Query(ctx, sql, args...)
+	t.observe(1, err)
+	if err != nil {
+		return rows, err
+	}
+
+	return &instrumentedTxRows{Rows: rows, tx: t}, nil
+}
+
+func (t *instrumentedTx) QueryRow(ctx context.Context, sql string, args ...interface{}) pgx.Row {
+	row := t.Tx.QueryRow(ctx, sql, args...)
+	t.observe(1, nil)
+
+	return &instrumentedTxRow{row: row, tx: t}
+}
+
+// instrumentedTxRows checks error of rows of the transaction when they are closed.
+type instrumentedTxRows struct {
+	pgx.Rows
+	tx *instrumentedTx
+}
+
+func (r *instrumentedTxRows) Next() bool {
+	if r.Rows.Next() {
+		return true
+	}
+
+	r.tx.observe(0, r.Rows.Err())
+	return false
+}
+
+func (r *instrumentedTxRows) Close() {
+	r.Rows.Close()
+	r.tx.observe(0, r.Rows.Err())
+}
+
+// instrumentedTxRow checks error of row of the transaction when it is scanned.
+type instrumentedTxRow struct {
+	row pgx.Row
+	tx  *instrumentedTx
+}
+
+func (r *instrumentedTxRow) Scan(dest ...interface{}) error {
+	err := r.row.Scan(dest...)
+	r.tx.observe(0, err)
+
+	return err
+}
+
+// instrumentedBatchResults checks errors of batched statements of the transaction.
+type instrumentedBatchResults struct {
+	pgx.BatchResults
+	tx *instrumentedTx
+}
+
+func (r *instrumentedBatchResults) Exec() (pgconn.CommandTag, error) {
+	tag, err := r.BatchResults.Exec()
+	r.tx.observe(0, err)
+
+	return tag, err
+}
+
+func (r *instrumentedBatchResults) Query() (pgx.Rows, error) {
+	rows, err := r.BatchResults.Query()
+	r.tx.observe(0, err)
+	if err != nil {
+		return rows, err
+	}
+
+	return &instrumentedTxRows{Rows: rows, tx: r.tx}, nil
+}
+
+func (r *instrumentedBatchResults) QueryRow() pgx.Row {
+	return &instrumentedTxRow{row: r.BatchResults.QueryRow(), tx: r.tx}
+}
+
+func (r *instrumentedBatchResults) Close() error {
+	err := r.BatchResults.Close()
+	r.tx.observe(0, err)
+
+	return err
+}
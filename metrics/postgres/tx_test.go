@@ -0,0 +1,287 @@
+package postgres_test
+
+import (
+	"context"
+	"errors"
+	"github.com/jackc/pgconn"
+	"github.com/jackc/pgx/v4"
+	"github.com/jackc/pgx/v4/pgxpool"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/weaponry/go-instrumenting/metrics"
+	"github.com/weaponry/go-instrumenting/metrics/metricstest"
+	postgresmetrics "github.com/weaponry/go-instrumenting/metrics/postgres"
+	"testing"
+)
+
+// Pools and connections should be wrapped by NewTxBeginner.
+var (
+	_ postgresmetrics.TxBeginner = (*pgxpool.Pool)(nil)
+	_ postgresmetrics.TxBeginner = (*pgx.Conn)(nil)
+)
+
+// fakeBeginner begins fake transactions.
+type fakeBeginner struct {
+	tx  *fakeTx
+	err error
+}
+
+func (b *fakeBeginner) Begin(ctx context.Context) (pgx.Tx, error) {
+	return b.BeginTx(ctx, pgx.TxOptions{})
+}
+
+func (b *fakeBeginner) BeginTx(context.Context, pgx.TxOptions) (pgx.Tx, error) {
+	if b.err != nil {
+		return nil, b.err
+	}
+	return b.tx, nil
+}
+
+// fakeTx implements used methods of pgx.Tx, statements fail with execErr and commit fails with commitErr.
+type fakeTx struct {
+	pgx.Tx
+	execErr   error
+	commitErr error
+	closed    bool
+}
+
+func (tx *fakeTx) Begin(context.Context) (pgx.Tx, error) {
+	return &fakeTx{execErr: tx.execErr}, nil
+}
+
+func (tx *fakeTx) Commit(context.Context) error {
+	if tx.closed {
+		return pgx.ErrTxClosed
+	}
+	tx.closed = true
+	return tx.commitErr
+}
+
+func (tx *fakeTx) Rollback(context.Context) error {
+	if tx.closed {
+		return pgx.ErrTxClosed
+	}
+	tx.closed = true
+	return nil
+}
+
+func (tx *fakeTx) Exec(context.Context, string, ...interface{}) (pgconn.CommandTag, error) {
+	return pgconn.CommandTag("UPDATE 1"), tx.execErr
+}
+
+func (tx *fakeTx) Query(context.Context, string, ...interface{}) (pgx.Rows, error) {
+	return &fakeRows{left: 1, err: tx.execErr}, nil
+}
+
+func (tx *fakeTx) QueryRow(context.Context, string, ...interface{}) pgx.Row {
+	return &fakeRows{left: 1, err: tx.execErr}
+}
+
+func (tx *fakeTx) SendBatch(context.Context, *pgx.Batch) pgx.BatchResults {
+	return &fakeBatchResults{err: tx.execErr}
+}
+
+// fakeBatchResults implements used methods of pgx.BatchResults.
+type fakeBatchResults struct {
+	pgx.BatchResults
+	err error
+}
+
+func (r *fakeBatchResults) Close() error { return r.err }
+
+func TestNewTxBeginner(t *testing.T) {
+	serializationFailure := &pgconn.PgError{Code: "40001"}
+
+	testCases := []struct {
+		name          string
+		tx            *fakeTx
+		options       pgx.TxOptions
+		run           func(ctx context.Context, tx pgx.Tx)
+		expIsolation  string
+		expOutcome    string
+		expStatements int
+	}{
+		{
+			name: "Committed transactions should be recorded with the number of statements.",
+			tx:   &fakeTx{},
+			run: func(ctx context.Context, tx pgx.Tx) {
+				_, _ = tx.Exec(ctx, "UPDATE users SET a = 1")
+				rows, _ := tx.Query(ctx, "SELECT id FROM users")
+				rows.Close()
+				_ = tx.Commit(ctx)
+			},
+			expIsolation:  "default",
+			expOutcome:    "commit",
+			expStatements: 2,
+		},
+		{
+			name:    "Rolled back transactions should be labelled by isolation level.",
+			tx:      &fakeTx{},
+			options: pgx.TxOptions{IsoLevel: pgx.RepeatableRead},
+			run: func(ctx context.Context, tx pgx.Tx) {
+				var id int
+				_ = tx.QueryRow(ctx, "SELECT id FROM users").Scan(&id)
+				_ = tx.Rollback(ctx)
+			},
+			expIsolation:  "repeatable_read",
+			expOutcome:    "rollback",
+			expStatements: 1,
+		},
+		{
+			name: "Transactions should be recorded only once.",
+			tx:   &fakeTx{},
+			run: func(ctx context.Context, tx pgx.Tx) {
+				_ = tx.Commit(ctx)
+				_ = tx.Rollback(ctx)
+			},
+			expIsolation: "default",
+			expOutcome:   "commit",
+		},
+		{
+			name: "Statements of batches and nested transactions should be counted.",
+			tx:   &fakeTx{},
+			run: func(ctx context.Context, tx pgx.Tx) {
+				b := &pgx.Batch{}
+				b.Queue("INSERT INTO users(name) VALUES ('a')")
+				b.Queue("INSERT INTO users(name) VALUES ('b')")
+				_ = tx.SendBatch(ctx, b).Close()
+
+				nested, _ := tx.Begin(ctx)
+				_, _ = nested.Exec(ctx, "UPDATE users SET a = 1")
+				_ = nested.Commit(ctx)
+				_ = tx.Commit(ctx)
+			},
+			expIsolation:  "default",
+			expOutcome:    "commit",
+			expStatements: 3,
+		},
+		{
+			name: "Failed commits should be recorded.",
+			tx:   &fakeTx{commitErr: pgx.ErrTxCommitRollback},
+			run: func(ctx context.Context, tx pgx.Tx) {
+				_, _ = tx.Exec(ctx, "UPDATE users SET a = 1")
+				_ = tx.Commit(ctx)
+			},
+			expIsolation:  "default",
+			expOutcome:    "commit_failed",
+			expStatements: 1,
+		},
+		{
+			name:    "Serialization failures of commits should be recorded.",
+			tx:      &fakeTx{commitErr: serializationFailure},
+			options: pgx.TxOptions{IsoLevel: pgx.Serializable},
+			run: func(ctx context.Context, tx pgx.Tx) {
+				_ = tx.Commit(ctx)
+			},
+			expIsolation: "serializable",
+			expOutcome:   "serialization_failure",
+		},
+		{
+			name:    "Serialization failures of statements should be recorded.",
+			tx:      &fakeTx{execErr: serializationFailure},
+			options: pgx.TxOptions{IsoLevel: pgx.Serializable},
+			run: func(ctx context.Context, tx pgx.Tx) {
+				var id int
+				_ = tx.QueryRow(ctx, "SELECT id FROM users").Scan(&id)
+				_ = tx.Rollback(ctx)
+			},
+			expIsolation:  "serializable",
+			expOutcome:    "serialization_failure",
+			expStatements: 1,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			recorder := metricstest.NewPostgresRecorder()
+			ctx := context.Background()
+
+			tx, err := postgresmetrics.NewTxBeginner(&fakeBeginner{tx: tc.tx}, recorder).BeginTx(ctx, tc.options)
+			require.NoError(t, err)
+			tc.run(ctx, tx)
+
+			calls := recorder.TxCalls()
+			require.Len(t, calls, 1)
+			assert.Equal(t, metrics.PostgresTxProperties{Isolation: tc.expIsolation, Outcome: tc.expOutcome}, calls[0].Props)
+			assert.Equal(t, tc.expStatements, calls[0].Statements)
+		})
+	}
+}
+
+func TestNewTxBeginnerMetrics(t *testing.T) {
+	registry := prometheus.NewRegistry()
+	recorder, err := postgresmetrics.RegisterPostgresRecorder("test-app", postgresmetrics.Config{Options: metrics.Options{Registerer: registry}})
+	require.NoError(t, err)
+
+	beginner := postgresmetrics.NewTxBeginner(&fakeBeginner{tx: &fakeTx{}}, recorder)
+	ctx := context.Background()
+
+	tx, err := beginner.BeginTx(ctx, pgx.TxOptions{IsoLevel: pgx.Serializable})
+	require.NoError(t, err)
+	_, _ = tx.Exec(ctx, "UPDATE users SET a = 1")
+	_, _ = tx.Exec(ctx, "UPDATE users SET b = 2")
+	require.NoError(t, tx.Commit(ctx))
+
+	labels := prometheus.Labels{"isolation": "serializable", "outcome": "commit"}
+	metricstest.AssertCounter(t, registry, "app_postgres_transactions_total", labels, 1)
+	metricstest.AssertHistogramCount(t, registry, "app_postgres_transaction_duration_seconds", labels, 1)
+	assert.Equal(t, float64(2), metricstest.HistogramSum(t, registry, "app_postgres_transaction_statements", labels))
+
+	// Transactions which have not begun should not be recorded.
+	_, err = postgresmetrics.NewTxBeginner(&fakeBeginner{err: errors.New("conn closed")}, recorder).BeginTx(ctx, pgx.TxOptions{})
+	assert.Error(t, err)
+	metricstest.AssertLabelSets(t, registry, "app_postgres_transactions_total", prometheus.Labels{
+		"application": "test-app", "isolation": "serializable", "outcome": "commit",
+	})
+}
+
+func TestNewTxBeginnerBegin(t *testing.T) {
+	recorder := metricstest.NewPostgresRecorder()
+	ctx := context.Background()
+
+	// Deferred rollback after commit should not record the transaction again.
+	func() {
+		tx, err := postgresmetrics.NewTxBeginner(&fakeBeginner{tx: &fakeTx{}}, recorder).Begin(ctx)
+		require.NoError(t, err)
+		defer func() { _ = tx.Rollback(ctx) }()
+
+		_, _ = tx.Exec(ctx, "UPDATE users SET a = 1")
+		require.NoError(t, tx.Commit(ctx))
+	}()
+
+	calls := recorder.TxCalls()
+	require.Len(t, calls, 1)
+	assert.Equal(t, metrics.PostgresTxProperties{Isolation: "default", Outcome: "commit"}, calls[0].Props)
+	assert.Equal(t, 1, calls[0].Statements)
+
+	// Transactions which have not begun should not be recorded.
+	_, err := postgresmetrics.NewTxBeginner(&fakeBeginner{err: errors.New("conn closed")}, recorder).Begin(ctx)
+	assert.Error(t, err)
+	assert.Len(t, recorder.TxCalls(), 1)
+}
+
+func TestWrapTx(t *testing.T) {
+	recorder := metricstest.NewPostgresRecorder()
+	ctx := context.Background()
+
+	tx := postgresmetrics.WrapTx(&fakeTx{}, pgx.ReadCommitted, recorder)
+	_, _ = tx.Exec(ctx, "UPDATE users SET a = 1")
+	_, _ = tx.Exec(ctx, "UPDATE users SET b = 2")
+	_ = tx.Rollback(ctx)
+	_ = tx.Rollback(ctx)
+
+	calls := recorder.TxCalls()
+	require.Len(t, calls, 1)
+	assert.Equal(t, metrics.PostgresTxProperties{Isolation: "read_committed", Outcome: "rollback"}, calls[0].Props)
+	assert.Equal(t, 2, calls[0].Statements)
+}
+
+func TestTxBaseRecorder(t *testing.T) {
+	// Transactions should not be wrapped if recorder doesn't record them.
+	beginner := &fakeBeginner{tx: &fakeTx{}}
+	assert.Same(t, beginner, postgresmetrics.NewTxBeginner(beginner, basePostgresRecorder{}))
+
+	tx := &fakeTx{}
+	assert.Same(t, tx, postgresmetrics.WrapTx(tx, pgx.ReadCommitted, basePostgresRecorder{}))
+}
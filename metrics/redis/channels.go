@@ -0,0 +1,109 @@
+package redis
+
+// channelOther is a value of the channel label used for channels which are not matched by known patterns.
+const channelOther = "other"
+
+// channels resolves values of the channel label of Pub/Sub messages and subscriptions.
+type channels struct {
+	patterns []string
+}
+
+// newChannels creates channels labelled by the first matching pattern of known patterns, all channels are labelled
+// as 'other' if there are no known patterns, since names of channels are often unbounded (e.g. 'invalidate:user:42').
+func newChannels(patterns []string) *channels {
+	return &channels{patterns: patterns}
+}
+
+// resolve returns channel label of the channel or pattern. Subscribed patterns are resolved like channels, hence
+// known patterns are labelled by themselves.
+func (c *channels) resolve(channel string) string {
+	for _, pattern := range c.patterns {
+		if matchPattern(pattern, channel) {
+			return pattern
+		}
+	}
+
+	return channelOther
+}
+
+// matchPattern reports whether s matches glob-style pattern as Redis matches patterns of PSUBSCRIBE: '*' matches any
+// sequence, '?' matches any character, '[...]' matches characters of the set ('^' negates the set, 'a-z' is a range)
+// and '\' escapes special characters.
+func matchPattern(pattern, s string) bool {
+	for len(pattern) > 0 {
+		switch pattern[0] {
+		case '*':
+			for len(pattern) > 1 && pattern[1] == '*' {
+				pattern = pattern[1:]
+			}
+			if len(pattern) == 1 {
+				return true
+			}
+			for i := 0; i <= len(s); i++ {
+				if matchPattern(pattern[1:], s[i:]) {
+					return true
+				}
+			}
+			return false
+		case '?':
+			if len(s) == 0 {
+				return false
+			}
+			pattern, s = pattern[1:], s[1:]
+		case '[':
+			if len(s) == 0 {
+				return false
+			}
+			var matched bool
+			matched, pattern = matchSet(pattern[1:], s[0])
+			if !matched {
+				return false
+			}
+			s = s[1:]
+		default:
+			if pattern[0] == '\\' && len(pattern) > 1 {
+				pattern = pattern[1:]
+			}
+			if len(s) == 0 || pattern[0] != s[0] {
+				return false
+			}
+			pattern, s = pattern[1:], s[1:]
+		}
+	}
+
+	return len(s) == 0
+}
+
+// matchSet reports whether c matches the set at the beginning of pattern (after '['), and returns the rest of pattern
+// after the set. Unterminated sets end with pattern.
+func matchSet(pattern string, c byte) (bool, string) {
+	var negate, matched bool
+	if len(pattern) > 0 && pattern[0] == '^' {
+		negate = true
+		pattern = pattern[1:]
+	}
+
+	for len(pattern) > 0 && pattern[0] != ']' {
+		switch {
+		case pattern[0] == '\\' && len(pattern) > 1:
+			matched = matched || pattern[1] == c
+			pattern = pattern[2:]
+		case len(pattern) > 2 && pattern[1] == '-' && pattern[2] != ']':
+			lo, hi := pattern[0], pattern[2]
+			if lo > hi {
+				lo, hi = hi, lo
+			}
+			matched = matched || lo <= c && c <= hi
+			pattern = pattern[3:]
+		default:
+			matched = matched || pattern[0] == c
+			pattern = pattern[1:]
+		}
+	}
+
+	if len(pattern) > 0 {
+		pattern = pattern[1:]
+	}
+
+	return matched != negate, pattern
+}
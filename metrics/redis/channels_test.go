@@ -0,0 +1,84 @@
+package redis_test
+
+import (
+	"github.com/go-redis/redis/v7"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/weaponry/go-instrumenting/metrics"
+	"github.com/weaponry/go-instrumenting/metrics/metricstest"
+	redismetrics "github.com/weaponry/go-instrumenting/metrics/redis"
+	"testing"
+	"time"
+)
+
+func TestChannels(t *testing.T) {
+	testCases := []struct {
+		name       string
+		channels   []string
+		channel    string
+		expChannel string
+	}{
+		{
+			name:       "Channels should be labelled as other without known patterns.",
+			channel:    "cache/users/42",
+			expChannel: "other",
+		},
+		{
+			name:       "Channels should be labelled by the first matching pattern.",
+			channels:   []string{"cache/users", "cache/*", "*"},
+			channel:    "cache/users/42",
+			expChannel: "cache/*",
+		},
+		{
+			name:       "Unknown channels should be labelled as other.",
+			channels:   []string{"cache/*"},
+			channel:    "events/orders",
+			expChannel: "other",
+		},
+		{
+			name:       "Single characters should be matched by question marks.",
+			channels:   []string{"shard-?/invalidate"},
+			channel:    "shard-7/invalidate",
+			expChannel: "shard-?/invalidate",
+		},
+		{
+			name:       "Characters should be matched by sets and ranges.",
+			channels:   []string{"shard-[0-4]", "shard-[^0-4]"},
+			channel:    "shard-7",
+			expChannel: "shard-[^0-4]",
+		},
+		{
+			name:       "Escaped characters should be matched literally.",
+			channels:   []string{`cache\*`, "*"},
+			channel:    "cache/users",
+			expChannel: "*",
+		},
+		{
+			name:       "Patterns should match the whole channel.",
+			channels:   []string{"cache"},
+			channel:    "cache/users",
+			expChannel: "other",
+		},
+		{
+			name:       "Known patterns should be labelled by themselves.",
+			channels:   []string{"cache/*"},
+			channel:    "cache/*",
+			expChannel: "cache/*",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			registry := prometheus.NewRegistry()
+			recorder := redismetrics.NewRedisRecorder("test-app", redismetrics.Config{
+				Options:  metrics.Options{Registerer: registry},
+				Channels: tc.channels,
+			})
+
+			redismetrics.NewObserver(recorder).ObserveCommand(redis.NewIntCmd("publish", tc.channel, "1"), time.Millisecond)
+
+			metricstest.AssertLabelSets(t, registry, "app_redis_pubsub_published_messages_total", prometheus.Labels{
+				"application": "test-app", "channel": tc.expChannel, "status": "ok",
+			})
+		})
+	}
+}
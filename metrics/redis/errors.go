@@ -0,0 +1,102 @@
+package redis
+
+import (
+	"context"
+	"errors"
+	"github.com/go-redis/redis/v7"
+	"net"
+	"strings"
+	"syscall"
+)
+
+// Values of the status label.
+const (
+	statusOK          = "ok"
+	statusNil         = "nil"
+	statusTimeout     = "timeout"
+	statusCanceled    = "canceled"
+	statusConnRefused = "conn_refused"
+	statusMoved       = "moved"
+	statusAsk         = "ask"
+	statusReadOnly    = "readonly"
+	statusLoading     = "loading"
+	statusWrongType   = "wrongtype"
+	statusOOM         = "oom"
+	statusNoScript    = "noscript"
+	statusOther       = "other"
+)
+
+// redisErrorPrefixes maps prefixes of errors replied by Redis to values of the status label.
+var redisErrorPrefixes = []struct {
+	prefix string
+	status string
+}{
+	{"MOVED ", statusMoved},
+	{"ASK ", statusAsk},
+	{"READONLY ", statusReadOnly},
+	{"LOADING ", statusLoading},
+	{"WRONGTYPE ", statusWrongType},
+	{"OOM ", statusOOM},
+	{"NOSCRIPT ", statusNoScript},
+}
+
+// poolTimeoutMessage is a message of pool.ErrPoolTimeout returned by go-redis when no connection of the pool becomes
+// available within PoolTimeout. The error is internal to go-redis v7.4.0, v8.11.5 and v9.0.5 (required by this module,
+// metrics/redis/redisv8 and metrics/redis/redisv9), hence it is matched by the message, which is the same in all of
+// them. Check the message when upgrading go-redis and compare with the error instead once it is exported.
+const poolTimeoutMessage = "redis: connection pool timeout"
+
+// ClassifyError maps error of the command to a value of the status label. Missing keys (redis.Nil) are reported
+// as 'nil' instead of errors, exhaustion of the connection pool is reported as 'timeout'. Errors of all supported
+// versions of go-redis are classified.
+func ClassifyError(err error) string {
+	if err == nil {
+		return statusOK
+	}
+
+	if isNil(err) {
+		return statusNil
+	}
+
+	var (
+		redisErr redis.Error
+		netErr   net.Error
+	)
+
+	switch {
+	case errors.Is(err, context.Canceled):
+		return statusCanceled
+	case errors.Is(err, context.DeadlineExceeded), errors.As(err, &netErr) && netErr.Timeout(), isPoolTimeout(err):
+		return statusTimeout
+	case errors.Is(err, syscall.ECONNREFUSED):
+		return statusConnRefused
+	case errors.As(err, &redisErr):
+		for _, p := range redisErrorPrefixes {
+			if strings.HasPrefix(redisErr.Error(), p.prefix) {
+				return p.status
+			}
+		}
+	}
+
+	return statusOther
+}
+
+// isNil reports whether err is redis.Nil of any supported version of go-redis.
+func isNil(err error) bool {
+	if err == redis.Nil {
+		return true
+	}
+
+	_, ok := err.(redis.Error)
+	return ok && err.Error() == redis.Nil.Error()
+}
+
+// isPoolTimeout reports whether err is the pool timeout error of any supported version of go-redis.
+func isPoolTimeout(err error) bool {
+	for ; err != nil; err = errors.Unwrap(err) {
+		if err.Error() == poolTimeoutMessage {
+			return true
+		}
+	}
+	return false
+}
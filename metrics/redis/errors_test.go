@@ -0,0 +1,101 @@
+package redis_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/go-redis/redis/v7"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/weaponry/go-instrumenting/metrics"
+	"github.com/weaponry/go-instrumenting/metrics/metricstest"
+	redismetrics "github.com/weaponry/go-instrumenting/metrics/redis"
+	"net"
+	"os"
+	"syscall"
+	"testing"
+	"time"
+)
+
+// redisError is an error replied by Redis server.
+type redisError string
+
+func (e redisError) Error() string { return string(e) }
+
+func (redisError) RedisError() {}
+
+func TestClassifyError(t *testing.T) {
+	testCases := []struct {
+		err       error
+		expStatus string
+	}{
+		{err: nil, expStatus: "ok"},
+		{err: redis.Nil, expStatus: "nil"},
+		{err: redisError("redis: nil"), expStatus: "nil"}, // redis.Nil of other versions of go-redis
+		{err: errors.New("redis: nil"), expStatus: "other"},
+		{err: context.Canceled, expStatus: "canceled"},
+		{err: context.DeadlineExceeded, expStatus: "timeout"},
+		{err: &net.OpError{Op: "read", Net: "tcp", Err: os.NewSyscallError("read", syscall.ETIMEDOUT)}, expStatus: "timeout"},
+		{err: errors.New("redis: connection pool timeout"), expStatus: "timeout"},
+		{err: fmt.Errorf("get user: %w", errors.New("redis: connection pool timeout")), expStatus: "timeout"},
+		{err: &net.OpError{Op: "dial", Net: "tcp", Err: os.NewSyscallError("connect", syscall.ECONNREFUSED)}, expStatus: "conn_refused"},
+		{err: redisError("MOVED 3999 127.0.0.1:6381"), expStatus: "moved"},
+		{err: redisError("ASK 3999 127.0.0.1:6381"), expStatus: "ask"},
+		{err: redisError("READONLY You can't write against a read only replica."), expStatus: "readonly"},
+		{err: redisError("LOADING Redis is loading the dataset in memory"), expStatus: "loading"},
+		{err: redisError("WRONGTYPE Operation against a key holding the wrong kind of value"), expStatus: "wrongtype"},
+		{err: redisError("OOM command not allowed when used memory > 'maxmemory'."), expStatus: "oom"},
+		{err: redisError("NOSCRIPT No matching script. Please use EVAL."), expStatus: "noscript"},
+		{err: redisError("ERR unknown command 'foo'"), expStatus: "other"},
+		{err: errors.New("WRONGTYPE not replied by server"), expStatus: "other"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(fmt.Sprintf("%v", tc.err), func(t *testing.T) {
+			assert.Equal(t, tc.expStatus, redismetrics.ClassifyError(tc.err))
+		})
+	}
+}
+
+func TestCollectHookErrorClassifier(t *testing.T) {
+	registry := prometheus.NewRegistry()
+	recorder := redismetrics.NewRedisRecorder("test-app", redismetrics.Config{
+		Options: metrics.Options{Registerer: registry},
+		ErrorClassifier: func(err error) string {
+			if err == redis.Nil {
+				return "miss"
+			}
+			return redismetrics.ClassifyError(err)
+		},
+	})
+	hook := recorder.NewCollectHook()
+
+	for _, err := range []error{nil, redis.Nil, redisError("WRONGTYPE Operation against a key holding the wrong kind of value")} {
+		cmd := redis.NewStringCmd("get", "app/users/1")
+		cmd.SetErr(err)
+
+		ctx, err := hook.BeforeProcess(context.Background(), cmd)
+		require.NoError(t, err)
+		require.NoError(t, hook.AfterProcess(ctx, cmd))
+	}
+
+	for _, status := range []string{"ok", "miss", "wrongtype"} {
+		metricstest.AssertCounter(t, registry, "app_redis_requests_total", prometheus.Labels{"keyspace": "/users", "status": status}, 1)
+	}
+}
+
+func TestClassifyErrorPoolTimeout(t *testing.T) {
+	client := redis.NewClient(&redis.Options{PoolSize: 1, PoolTimeout: 10 * time.Millisecond, Dialer: pongDialer()})
+	defer client.Close()
+
+	// The only connection of the pool is taken by the sticky connection.
+	conn := client.Conn()
+	defer conn.Close()
+	require.NoError(t, conn.Ping().Err())
+
+	// Message of the error internal to go-redis should be still known.
+	err := client.Ping().Err()
+	require.Error(t, err)
+	assert.Equal(t, "timeout", redismetrics.ClassifyError(err))
+}
@@ -0,0 +1,146 @@
+package redis_test
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"github.com/go-redis/redis/v7"
+	"net"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// newFakeRedis returns client connected to in-memory server which serves read commands from passed strings and hashes.
+func newFakeRedis(t *testing.T, strs map[string]string, hashes map[string]map[string]string) *redis.Client {
+	client := redis.NewClient(&redis.Options{Dialer: fakeRedisDialer(strs, hashes)})
+	t.Cleanup(func() { _ = client.Close() })
+
+	return client
+}
+
+// fakeRedisDialer returns dialer of connections to in-memory server which serves read commands from passed strings and
+// hashes, subscribers of channels receive the string with the name of the channel. Scripts are never cached, hence
+// EVALSHA always fails with NOSCRIPT and EVAL returns the number of keys.
+func fakeRedisDialer(strs map[string]string, hashes map[string]map[string]string) func(context.Context, string, string) (net.Conn, error) {
+	bulk := func(v string, ok bool) string {
+		if !ok {
+			return "$-1\r\n"
+		}
+		return fmt.Sprintf("$%d\r\n%s\r\n", len(v), v)
+	}
+
+	reply := func(args []string) string {
+		switch strings.ToLower(args[0]) {
+		case "get":
+			v, ok := strs[args[1]]
+			return bulk(v, ok)
+		case "mget":
+			r := fmt.Sprintf("*%d\r\n", len(args)-1)
+			for _, k := range args[1:] {
+				v, ok := strs[k]
+				r += bulk(v, ok)
+			}
+			return r
+		case "hget":
+			v, ok := hashes[args[1]][args[2]]
+			return bulk(v, ok)
+		case "hmget":
+			r := fmt.Sprintf("*%d\r\n", len(args)-2)
+			for _, f := range args[2:] {
+				v, ok := hashes[args[1]][f]
+				r += bulk(v, ok)
+			}
+			return r
+		case "hgetall":
+			r := fmt.Sprintf("*%d\r\n", 2*len(hashes[args[1]]))
+			for f, v := range hashes[args[1]] {
+				r += bulk(f, true) + bulk(v, true)
+			}
+			return r
+		case "exists":
+			n := 0
+			for _, k := range args[1:] {
+				if _, ok := strs[k]; ok {
+					n++
+				} else if _, ok := hashes[k]; ok {
+					n++
+				}
+			}
+			return fmt.Sprintf(":%d\r\n", n)
+		case "hexists":
+			if _, ok := hashes[args[1]][args[2]]; ok {
+				return ":1\r\n"
+			}
+			return ":0\r\n"
+		case "evalsha":
+			return "-NOSCRIPT No matching script. Please use EVAL.\r\n"
+		case "eval":
+			return ":" + args[2] + "\r\n"
+		case "subscribe":
+			var r string
+			for i, channel := range args[1:] {
+				r += "*3\r\n" + bulk("subscribe", true) + bulk(channel, true) + fmt.Sprintf(":%d\r\n", i+1)
+			}
+			for _, channel := range args[1:] {
+				if v, ok := strs[channel]; ok {
+					r += "*3\r\n" + bulk("message", true) + bulk(channel, true) + bulk(v, true)
+				}
+			}
+			return r
+		default:
+			return "-ERR unknown command\r\n"
+		}
+	}
+
+	return newFakeDialer(func(_ string, args []string) string { return reply(args) })
+}
+
+// newFakeDialer returns dialer of connections to in-memory server which replies to commands sent to the node with
+// passed address by reply.
+func newFakeDialer(reply func(addr string, args []string) string) func(context.Context, string, string) (net.Conn, error) {
+	serve := func(conn net.Conn, addr string) {
+		defer func() { _ = conn.Close() }()
+
+		r := bufio.NewReader(conn)
+		for {
+			line, err := r.ReadString('\n')
+			if err != nil {
+				return
+			}
+			n, _ := strconv.Atoi(strings.TrimSpace(line[1:]))
+
+			args := make([]string, 0, n)
+			for i := 0; i < n; i++ {
+				if _, err := r.ReadString('\n'); err != nil {
+					return
+				}
+				arg, err := r.ReadString('\n')
+				if err != nil {
+					return
+				}
+				args = append(args, strings.TrimSuffix(arg, "\r\n"))
+			}
+
+			if _, err := conn.Write([]byte(reply(addr, args))); err != nil {
+				return
+			}
+		}
+	}
+
+	return func(_ context.Context, _, addr string) (net.Conn, error) {
+		clientConn, serverConn := net.Pipe()
+		go serve(serverConn, addr)
+		return clientConn, nil
+	}
+}
+
+// pongDialer returns dialer of connections to in-memory servers which reply only to PING.
+func pongDialer() func(context.Context, string, string) (net.Conn, error) {
+	return newFakeDialer(func(_ string, args []string) string {
+		if strings.ToLower(args[0]) == "ping" {
+			return "+PONG\r\n"
+		}
+		return "-ERR unknown command\r\n"
+	})
+}
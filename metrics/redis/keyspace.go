@@ -0,0 +1,205 @@
+package redis
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+const (
+	// keyspaceOther is a value of the keyspace label used for keyspaces which are not allowed by config.
+	keyspaceOther = "other"
+	// keyspaceMixed is a value of the keyspace label used for commands with keys of different keyspaces.
+	keyspaceMixed = "mixed"
+)
+
+// KeyspaceExtractor extracts keyspace of the key, which is used as a keyspace label of commands.
+type KeyspaceExtractor interface {
+	Keyspace(key string) string
+}
+
+// KeyspaceExtractorFunc is an adapter to allow the use of ordinary functions as KeyspaceExtractor.
+type KeyspaceExtractorFunc func(key string) string
+
+// Keyspace calls f(key).
+func (f KeyspaceExtractorFunc) Keyspace(key string) string {
+	return f(key)
+}
+
+// NewDelimiterExtractor returns KeyspaceExtractor which takes first depth parts of the key separated by delimiter,
+// e.g. 'app:users' for the key 'app:users:42' with ':' delimiter and depth 2.
+func NewDelimiterExtractor(delimiter string, depth int) KeyspaceExtractor {
+	return KeyspaceExtractorFunc(func(key string) string {
+		parts := strings.SplitN(key, delimiter, depth+1)
+		if len(parts) > depth {
+			parts = parts[:depth]
+		}
+		return strings.Join(parts, delimiter)
+	})
+}
+
+// NewRegexpExtractor returns KeyspaceExtractor which takes the first capture group of re matched in the key, or the
+// whole match if re has no groups. Keys not matched by re have empty keyspace.
+func NewRegexpExtractor(re *regexp.Regexp) KeyspaceExtractor {
+	return KeyspaceExtractorFunc(func(key string) string {
+		match := re.FindStringSubmatch(key)
+		switch {
+		case match == nil:
+			return ""
+		case len(match) > 1:
+			return match[1]
+		default:
+			return match[0]
+		}
+	})
+}
+
+// keyspaceRegexp is adjusted to the following format of keys - 'app-name/keyspace/...', hence using the regexp,
+// the app-name will be omitted (app-name is already passed as a part of metric) and rest of key will be extracted.
+// The group is non-capturing, so the whole match is taken rather than its last segment.
+var keyspaceRegexp = regexp.MustCompile(`(?:/[a-z-]{1,})+`)
+
+// DefaultKeyspaceExtractor extracts keyspaces from keys formatted as 'app-name/keyspace/...'.
+var DefaultKeyspaceExtractor = NewRegexpExtractor(keyspaceRegexp)
+
+// keyspaces resolves keyspace label of commands.
+type keyspaces struct {
+	extractor KeyspaceExtractor
+	allowed   map[string]bool
+}
+
+func newKeyspaces(extractor KeyspaceExtractor, allowed []string) *keyspaces {
+	k := &keyspaces{extractor: extractor}
+
+	if len(allowed) > 0 {
+		k.allowed = make(map[string]bool, len(allowed))
+		for _, keyspace := range allowed {
+			k.allowed[keyspace] = true
+		}
+	}
+
+	return k
+}
+
+// resolve returns keyspace of the command with passed arguments. Keyspace is empty for commands without keys, 'mixed'
+// for commands with keys of different keyspaces and 'other' for keyspaces which are not allowed.
+func (k *keyspaces) resolve(args []interface{}) string {
+	var result string
+
+	for i, key := range commandKeys(args) {
+		keyspace := k.extractor.Keyspace(key)
+		if k.allowed != nil && keyspace != "" && !k.allowed[keyspace] {
+			keyspace = keyspaceOther
+		}
+
+		if i == 0 {
+			result = keyspace
+		} else if keyspace != result {
+			return keyspaceMixed
+		}
+	}
+
+	return result
+}
+
+// Positions of keys in arguments of commands.
+const (
+	keysFirst = iota
+	keysNone
+	keysAll
+	keysAllButLast
+	keysPairs
+	keysScript
+	keysStreams
+)
+
+// commandKeyPositions describes positions of keys of commands which don't have the only key in the first argument.
+var commandKeyPositions = map[string]int{
+	"auth": keysNone, "bgsave": keysNone, "client": keysNone, "cluster": keysNone, "command": keysNone,
+	"config": keysNone, "dbsize": keysNone, "debug": keysNone, "discard": keysNone, "echo": keysNone,
+	"exec": keysNone, "flushall": keysNone, "flushdb": keysNone, "hello": keysNone, "info": keysNone,
+	"keys": keysNone, "lastsave": keysNone, "monitor": keysNone, "multi": keysNone, "ping": keysNone,
+	"psubscribe": keysNone, "publish": keysNone, "pubsub": keysNone, "punsubscribe": keysNone, "quit": keysNone,
+	"randomkey": keysNone, "readonly": keysNone, "readwrite": keysNone, "role": keysNone, "save": keysNone,
+	"scan": keysNone, "script": keysNone, "select": keysNone, "shutdown": keysNone, "slowlog": keysNone,
+	"subscribe": keysNone, "swapdb": keysNone, "time": keysNone, "unsubscribe": keysNone, "unwatch": keysNone,
+	"wait": keysNone,
+
+	"del": keysAll, "exists": keysAll, "mget": keysAll, "pfcount": keysAll, "pfmerge": keysAll, "rename": keysAll,
+	"renamenx": keysAll, "rpoplpush": keysAll, "sdiff": keysAll, "sdiffstore": keysAll, "sinter": keysAll,
+	"sinterstore": keysAll, "sunion": keysAll, "sunionstore": keysAll, "touch": keysAll, "unlink": keysAll,
+	"watch": keysAll,
+
+	"blpop": keysAllButLast, "brpop": keysAllButLast, "brpoplpush": keysAllButLast, "bzpopmax": keysAllButLast,
+	"bzpopmin": keysAllButLast, "smove": keysAllButLast,
+
+	"mset": keysPairs, "msetnx": keysPairs,
+
+	"eval": keysScript, "eval_ro": keysScript, "evalsha": keysScript, "evalsha_ro": keysScript,
+
+	"xread": keysStreams, "xreadgroup": keysStreams,
+}
+
+// commandKeys returns keys of the command with passed arguments.
+func commandKeys(args []interface{}) []string {
+	if len(args) < 2 {
+		return nil
+	}
+
+	var (
+		name = strings.ToLower(argString(args[0]))
+		keys []interface{}
+	)
+
+	switch commandKeyPositions[name] {
+	case keysNone:
+		return nil
+	case keysAll:
+		keys = args[1:]
+	case keysAllButLast:
+		keys = args[1 : len(args)-1]
+	case keysPairs:
+		for i := 1; i < len(args); i += 2 {
+			keys = append(keys, args[i])
+		}
+	case keysScript:
+		// EVAL script numkeys key [key ...] arg [arg ...]
+		if len(args) >= 3 {
+			numKeys, err := strconv.Atoi(argString(args[2]))
+			if err == nil && numKeys >= 0 && 3+numKeys <= len(args) {
+				keys = args[3 : 3+numKeys]
+			}
+		}
+	case keysStreams:
+		// XREAD [COUNT count] [BLOCK ms] STREAMS key [key ...] id [id ...]
+		for i := 1; i < len(args); i++ {
+			if strings.EqualFold(argString(args[i]), "streams") {
+				streams := args[i+1:]
+				keys = streams[:len(streams)/2]
+				break
+			}
+		}
+	default:
+		keys = args[1:2]
+	}
+
+	result := make([]string, 0, len(keys))
+	for _, key := range keys {
+		result = append(result, argString(key))
+	}
+
+	return result
+}
+
+// argString returns string representation of the command argument.
+func argString(arg interface{}) string {
+	switch v := arg.(type) {
+	case string:
+		return v
+	case []byte:
+		return string(v)
+	default:
+		return fmt.Sprint(v)
+	}
+}
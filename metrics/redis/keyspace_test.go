@@ -0,0 +1,172 @@
+package redis_test
+
+import (
+	"context"
+	"github.com/go-redis/redis/v7"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/weaponry/go-instrumenting/metrics"
+	"github.com/weaponry/go-instrumenting/metrics/metricstest"
+	redismetrics "github.com/weaponry/go-instrumenting/metrics/redis"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+func TestKeyspaceExtractors(t *testing.T) {
+	testCases := []struct {
+		name        string
+		extractor   redismetrics.KeyspaceExtractor
+		key         string
+		expKeyspace string
+	}{
+		{
+			name:        "Default extractor should omit app name and ids.",
+			extractor:   redismetrics.DefaultKeyspaceExtractor,
+			key:         "app/users/1",
+			expKeyspace: "/users",
+		},
+		{
+			name:        "Default extractor should take all segments of the keyspace.",
+			extractor:   redismetrics.DefaultKeyspaceExtractor,
+			key:         "app/users/profile",
+			expKeyspace: "/users/profile",
+		},
+		{
+			name:        "Default extractor should stop at the first id.",
+			extractor:   redismetrics.DefaultKeyspaceExtractor,
+			key:         "app/users/1/profile",
+			expKeyspace: "/users",
+		},
+		{
+			name:        "Delimiter extractor should take parts of the key up to depth.",
+			extractor:   redismetrics.NewDelimiterExtractor(":", 2),
+			key:         "App1:Users:42:profile",
+			expKeyspace: "App1:Users",
+		},
+		{
+			name:        "Delimiter extractor should take the whole key when it is shorter than depth.",
+			extractor:   redismetrics.NewDelimiterExtractor(":", 2),
+			key:         "config",
+			expKeyspace: "config",
+		},
+		{
+			name:        "Regexp extractor should take the first capture group.",
+			extractor:   redismetrics.NewRegexpExtractor(regexp.MustCompile(`^[^:]+:([^:]+)`)),
+			key:         "app:Orders2:42",
+			expKeyspace: "Orders2",
+		},
+		{
+			name:        "Regexp extractor should take the whole match without capture groups.",
+			extractor:   redismetrics.NewRegexpExtractor(regexp.MustCompile(`^[a-z]+`)),
+			key:         "orders:42",
+			expKeyspace: "orders",
+		},
+		{
+			name:        "Regexp extractor should return empty keyspace for not matched keys.",
+			extractor:   redismetrics.NewRegexpExtractor(regexp.MustCompile(`^[a-z]+:`)),
+			key:         "42",
+			expKeyspace: "",
+		},
+		{
+			name: "Custom functions should be used as extractors.",
+			extractor: redismetrics.KeyspaceExtractorFunc(func(key string) string {
+				return strings.ToUpper(key[:1])
+			}),
+			key:         "users:1",
+			expKeyspace: "U",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.expKeyspace, tc.extractor.Keyspace(tc.key))
+		})
+	}
+}
+
+func TestCollectHookKeyspaces(t *testing.T) {
+	testCases := []struct {
+		name        string
+		args        []interface{}
+		expKeyspace string
+	}{
+		{
+			name:        "Commands with a single key should use keyspace of the key.",
+			args:        []interface{}{"get", "app:users:1"},
+			expKeyspace: "users",
+		},
+		{
+			name:        "Commands without keys should have empty keyspace.",
+			args:        []interface{}{"select", "1"},
+			expKeyspace: "",
+		},
+		{
+			name:        "Keys of the same keyspace should not be mixed.",
+			args:        []interface{}{"mget", "app:users:1", "app:users:2"},
+			expKeyspace: "users",
+		},
+		{
+			name:        "Keys of different keyspaces should be mixed.",
+			args:        []interface{}{"del", "app:users:1", "app:orders:1"},
+			expKeyspace: "mixed",
+		},
+		{
+			name:        "Values of MSET should not be taken as keys.",
+			args:        []interface{}{"mset", "app:users:1", "app:orders:1", "app:users:2", "app:orders:2"},
+			expKeyspace: "users",
+		},
+		{
+			name:        "Timeouts of blocking commands should not be taken as keys.",
+			args:        []interface{}{"blpop", "app:jobs:1", "app:jobs:2", 5},
+			expKeyspace: "jobs",
+		},
+		{
+			name:        "Only KEYS of scripts should be taken into account.",
+			args:        []interface{}{"eval", "return 1", 2, "app:users:1", "app:users:2", "app:orders:1"},
+			expKeyspace: "users",
+		},
+		{
+			name:        "Scripts without keys should have empty keyspace.",
+			args:        []interface{}{"evalsha", "e0e1f9fabfc9d4800c877a703b823ac0578ff831", "0", "app:users:1"},
+			expKeyspace: "",
+		},
+		{
+			name:        "Only streams of XREAD should be taken into account.",
+			args:        []interface{}{"xread", "COUNT", 10, "STREAMS", "app:events:1", "app:events:2", "0", "0"},
+			expKeyspace: "events",
+		},
+		{
+			name:        "Keyspaces which are not allowed should be labelled as other.",
+			args:        []interface{}{"get", "app:sessions:1"},
+			expKeyspace: "other",
+		},
+		{
+			name:        "Keys of unknown keyspaces should be mixed with known ones.",
+			args:        []interface{}{"mget", "app:users:1", "app:sessions:1"},
+			expKeyspace: "mixed",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			registry := prometheus.NewRegistry()
+			recorder := redismetrics.NewRedisRecorder("test-app", redismetrics.Config{
+				Options:           metrics.Options{Registerer: registry},
+				KeyspaceExtractor: redismetrics.NewRegexpExtractor(regexp.MustCompile(`^[^:]+:([^:]+)`)),
+				Keyspaces:         []string{"users", "orders", "jobs", "events"},
+			})
+			hook := recorder.NewCollectHook()
+
+			cmd := redis.NewCmd(tc.args...)
+			ctx, err := hook.BeforeProcess(context.Background(), cmd)
+			require.NoError(t, err)
+			require.NoError(t, hook.AfterProcess(ctx, cmd))
+
+			metricstest.AssertLabelSets(t, registry, "app_redis_requests_total", prometheus.Labels{
+				"application": "test-app", "command": tc.args[0].(string), "keyspace": tc.expKeyspace, "pipeline": "none", "status": "ok",
+			})
+		})
+	}
+}
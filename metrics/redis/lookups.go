@@ -0,0 +1,117 @@
+package redis
+
+const (
+	labelResult = "result"
+
+	resultHit  = "hit"
+	resultMiss = "miss"
+)
+
+// lookups returns the number of found (hits) and missing (misses) keys or fields read by the command, failed commands
+// and commands which don't read cached values have neither hits nor misses.
+func lookups(cmd Cmder) (hits, misses int) {
+	if err := cmd.Err(); err != nil {
+		if isNil(err) && isLookup(cmd.Name()) {
+			return 0, 1
+		}
+		return 0, 0
+	}
+
+	switch cmd.Name() {
+	case "get", "getdel", "getex", "hget":
+		return 1, 0
+	case "mget", "hmget":
+		// Missing keys or fields are replied as nil elements.
+		for _, v := range sliceVal(cmd) {
+			if v == nil {
+				misses++
+			} else {
+				hits++
+			}
+		}
+		return hits, misses
+	case "hgetall":
+		// Missing hashes are replied as empty ones.
+		if n, ok := lenVal(cmd); ok {
+			if n == 0 {
+				return 0, 1
+			}
+			return 1, 0
+		}
+	case "exists":
+		// EXISTS replies the number of existing keys out of passed ones.
+		if n, ok := intVal(cmd); ok {
+			keys := len(cmd.Args()) - 1
+			if n > keys {
+				n = keys
+			}
+			return n, keys - n
+		}
+	case "hexists":
+		if n, ok := intVal(cmd); ok {
+			if n == 0 {
+				return 0, 1
+			}
+			return 1, 0
+		}
+	}
+
+	return 0, 0
+}
+
+// isLookup reports whether missing values of the command are replied as redis.Nil.
+func isLookup(name string) bool {
+	switch name {
+	case "get", "getdel", "getex", "hget":
+		return true
+	default:
+		return false
+	}
+}
+
+// sliceVal returns elements of array reply of the command.
+func sliceVal(cmd Cmder) []interface{} {
+	switch c := cmd.(type) {
+	case sliceReply:
+		return c.Val()
+	case cmdReply:
+		v, _ := c.Val().([]interface{})
+		return v
+	default:
+		return nil
+	}
+}
+
+// lenVal returns length of array or map reply of the command.
+func lenVal(cmd Cmder) (int, bool) {
+	switch c := cmd.(type) {
+	case stringMapReply:
+		return len(c.Val()), true
+	case cmdReply:
+		switch v := c.Val().(type) {
+		case []interface{}:
+			return len(v), true
+		case map[interface{}]interface{}:
+			return len(v), true
+		}
+	}
+	return 0, false
+}
+
+// intVal returns integer or boolean reply of the command.
+func intVal(cmd Cmder) (int, bool) {
+	switch c := cmd.(type) {
+	case intReply:
+		return int(c.Val()), true
+	case boolReply:
+		if c.Val() {
+			return 1, true
+		}
+		return 0, true
+	case cmdReply:
+		v, ok := c.Val().(int64)
+		return int(v), ok
+	default:
+		return 0, false
+	}
+}
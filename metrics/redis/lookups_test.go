@@ -0,0 +1,110 @@
+package redis_test
+
+import (
+	"github.com/go-redis/redis/v7"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/weaponry/go-instrumenting/metrics"
+	"github.com/weaponry/go-instrumenting/metrics/metricstest"
+	redismetrics "github.com/weaponry/go-instrumenting/metrics/redis"
+	"testing"
+)
+
+func TestCollectHookCacheLookups(t *testing.T) {
+	strs := map[string]string{"app/users/1": "alice", "app/users/2": "bob"}
+	hashes := map[string]map[string]string{"app/profiles/1": {"name": "alice"}}
+
+	testCases := []struct {
+		name      string
+		run       func(client *redis.Client)
+		expHits   map[string]float64
+		expMisses map[string]float64
+	}{
+		{
+			name: "Found and missing strings should be counted.",
+			run: func(client *redis.Client) {
+				client.Get("app/users/1")
+				client.Get("app/users/3")
+			},
+			expHits:   map[string]float64{"/users": 1},
+			expMisses: map[string]float64{"/users": 1},
+		},
+		{
+			name: "Each element of MGET should be counted.",
+			run: func(client *redis.Client) {
+				client.MGet("app/users/1", "app/users/2", "app/users/3")
+			},
+			expHits:   map[string]float64{"/users": 2},
+			expMisses: map[string]float64{"/users": 1},
+		},
+		{
+			name: "Fields of hashes should be counted.",
+			run: func(client *redis.Client) {
+				client.HGet("app/profiles/1", "name")
+				client.HGet("app/profiles/1", "email")
+				client.HMGet("app/profiles/1", "name", "email", "phone")
+				client.HExists("app/profiles/1", "name")
+			},
+			expHits:   map[string]float64{"/profiles": 3},
+			expMisses: map[string]float64{"/profiles": 3},
+		},
+		{
+			name: "Missing hashes should be counted as misses of HGETALL.",
+			run: func(client *redis.Client) {
+				client.HGetAll("app/profiles/1")
+				client.HGetAll("app/profiles/2")
+			},
+			expHits:   map[string]float64{"/profiles": 1},
+			expMisses: map[string]float64{"/profiles": 1},
+		},
+		{
+			name: "Each key of EXISTS should be counted.",
+			run: func(client *redis.Client) {
+				client.Exists("app/users/1", "app/users/2", "app/users/3")
+			},
+			expHits:   map[string]float64{"/users": 2},
+			expMisses: map[string]float64{"/users": 1},
+		},
+		{
+			name: "Generic commands should be counted.",
+			run: func(client *redis.Client) {
+				client.Do("mget", "app/users/1", "app/users/3")
+			},
+			expHits:   map[string]float64{"/users": 1},
+			expMisses: map[string]float64{"/users": 1},
+		},
+		{
+			name: "Commands of pipelines should be counted.",
+			run: func(client *redis.Client) {
+				pipe := client.Pipeline()
+				pipe.Get("app/users/1")
+				pipe.Get("app/users/3")
+				pipe.HGet("app/profiles/1", "name")
+				_, _ = pipe.Exec()
+			},
+			expHits:   map[string]float64{"/users": 1, "/profiles": 1},
+			expMisses: map[string]float64{"/users": 1},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			registry := prometheus.NewRegistry()
+			recorder := redismetrics.NewRedisRecorder("test-app", redismetrics.Config{Options: metrics.Options{Registerer: registry}})
+
+			client := newFakeRedis(t, strs, hashes)
+			client.AddHook(recorder.NewCollectHook())
+			tc.run(client)
+
+			var expSets []prometheus.Labels
+			for keyspace, hits := range tc.expHits {
+				metricstest.AssertCounter(t, registry, "app_redis_cache_lookups_total", prometheus.Labels{"keyspace": keyspace, "result": "hit"}, hits)
+				expSets = append(expSets, prometheus.Labels{"application": "test-app", "keyspace": keyspace, "result": "hit"})
+			}
+			for keyspace, misses := range tc.expMisses {
+				metricstest.AssertCounter(t, registry, "app_redis_cache_lookups_total", prometheus.Labels{"keyspace": keyspace, "result": "miss"}, misses)
+				expSets = append(expSets, prometheus.Labels{"application": "test-app", "keyspace": keyspace, "result": "miss"})
+			}
+			metricstest.AssertLabelSets(t, registry, "app_redis_cache_lookups_total", expSets...)
+		})
+	}
+}
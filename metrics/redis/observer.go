@@ -0,0 +1,231 @@
+package redis
+
+import (
+	"github.com/weaponry/go-instrumenting/metrics"
+	"net"
+	"strings"
+	"time"
+)
+
+// Cmder is a processed command of any supported version of go-redis (v7, v8 or v9).
+type Cmder interface {
+	Name() string
+	Args() []interface{}
+	Err() error
+}
+
+// Replies of commands of any supported version of go-redis, they are distinguished by type of returned value.
+type (
+	cmdReply       interface{ Val() interface{} }
+	stringReply    interface{ Val() string }
+	sliceReply     interface{ Val() []interface{} }
+	stringsReply   interface{ Val() []string }
+	stringMapReply interface{ Val() map[string]string }
+	intReply       interface{ Val() int64 }
+	boolReply      interface{ Val() bool }
+	floatReply     interface{ Val() float64 }
+	scanReply      interface{ Val() ([]string, uint64) }
+)
+
+// Observer builds properties of processed commands, pipelines, dials and topology events and passes them to recorder.
+// It is shared by hooks of all supported versions of go-redis.
+type Observer struct {
+	recorder      metrics.RedisRecorder
+	pipelines     metrics.RedisPipelineRecorder
+	dials         metrics.RedisDialRecorder
+	topology      metrics.RedisTopologyRecorder
+	pubsub        metrics.RedisPubSubRecorder
+	classifyError func(err error) string
+	keyspaces     *keyspaces
+	scripts       *scripts
+	channels      *channels
+	node          string
+}
+
+// NewObserver creates observer passing properties to recorder. Errors are classified, keyspaces are extracted,
+// scripts are named and channels are labelled as configured for recorders created by this package, otherwise
+// ClassifyError and DefaultKeyspaceExtractor are used, scripts are not named and channels are labelled as 'other'.
+// Pipelines, dials, topology events and Pub/Sub are passed to recorder if it implements the corresponding interface
+// of metrics package, commands of pipelines are passed to Collect otherwise.
+func NewObserver(r metrics.RedisRecorder) *Observer {
+	o := &Observer{
+		recorder:      r,
+		classifyError: ClassifyError,
+		keyspaces:     newKeyspaces(DefaultKeyspaceExtractor, nil),
+		scripts:       newScripts(nil),
+		channels:      newChannels(nil),
+	}
+
+	if rec, ok := r.(*recorder); ok {
+		o.classifyError = rec.classifyError
+		o.keyspaces = rec.keyspaces
+		o.scripts = rec.scripts
+		o.channels = rec.channels
+	}
+
+	o.pipelines, _ = r.(metrics.RedisPipelineRecorder)
+	o.dials, _ = r.(metrics.RedisDialRecorder)
+	o.topology, _ = r.(metrics.RedisTopologyRecorder)
+	o.pubsub, _ = r.(metrics.RedisPubSubRecorder)
+
+	return o
+}
+
+// ForNode returns observer of commands served by the node with passed address, redirects and reloads of cluster slots
+// replied by the node are recorded as topology events.
+func (o *Observer) ForNode(addr string) *Observer {
+	n := *o
+	n.node = addr
+	return &n
+}
+
+// ObserveCommand records the processed command.
+func (o *Observer) ObserveCommand(cmd Cmder, duration time.Duration) {
+	o.observeTopology(cmd)
+	o.observePublish(cmd)
+	o.recorder.Collect(o.properties(cmd), duration)
+}
+
+// ObservePipeline records the processed pipeline, transactions are recognized by MULTI/EXEC commands.
+func (o *Observer) ObservePipeline(cmds []Cmder, duration time.Duration) {
+	var props = metrics.RedisPipelineProperties{
+		Type: pipelineTypePipeline,
+		Code: statusOK,
+		Node: o.node,
+	}
+
+	// Transactions are wrapped into MULTI/EXEC commands, which are not interesting on their own.
+	if len(cmds) >= 2 && cmds[0].Name() == "multi" && cmds[len(cmds)-1].Name() == "exec" {
+		props.Type = pipelineTypeTx
+		props.Code = o.classifyError(cmds[len(cmds)-1].Err())
+		cmds = cmds[1 : len(cmds)-1]
+	}
+
+	// Status of the pipeline is the status of its first failed command, missing keys are not failures.
+	var cmdsProps = make([]metrics.RedisReqProperties, 0, len(cmds))
+	for _, cmd := range cmds {
+		o.observeTopology(cmd)
+		o.observePublish(cmd)
+		cmdProps := o.properties(cmd)
+		if props.Code == statusOK && cmdProps.Code != statusNil {
+			props.Code = cmdProps.Code
+		}
+		cmdsProps = append(cmdsProps, cmdProps)
+	}
+
+	if o.pipelines == nil {
+		for _, cmdProps := range cmdsProps {
+			o.recorder.Collect(cmdProps, duration)
+		}
+		return
+	}
+
+	o.pipelines.CollectPipeline(props, cmdsProps, duration)
+}
+
+// ObserveDial records the dial of connection to the node with passed address.
+func (o *Observer) ObserveDial(addr string, err error, duration time.Duration) {
+	if o.dials == nil {
+		return
+	}
+
+	o.dials.CollectDial(metrics.RedisDialProperties{Node: addr, Code: o.classifyError(err)}, duration)
+}
+
+// ObserveSwitchMaster records failover of the master reported by Sentinel in '+switch-master' message with passed
+// payload ('<master name> <old ip> <old port> <new ip> <new port>'), failovers of other masters are ignored unless
+// masterName is empty.
+func (o *Observer) ObserveSwitchMaster(masterName string, payload string) {
+	parts := strings.Split(payload, " ")
+	if o.topology == nil || len(parts) != 5 || masterName != "" && parts[0] != masterName {
+		return
+	}
+
+	o.topology.CollectTopology(metrics.RedisTopologyProperties{
+		Node:  net.JoinHostPort(parts[3], parts[4]),
+		Event: eventFailover,
+	})
+}
+
+// ObserveMessage records the Pub/Sub message received from the channel, pattern is set for messages received by
+// pattern subscriptions. Lag is the time the message waited for the receiver, it is not observed if it is negative.
+func (o *Observer) ObserveMessage(channel, pattern string, lag time.Duration) {
+	if o.pubsub == nil {
+		return
+	}
+
+	if pattern != "" {
+		channel = pattern
+	}
+
+	o.pubsub.CollectReceive(metrics.RedisPubSubProperties{Channel: o.channels.resolve(channel)}, lag)
+}
+
+// ObserveSubscriptions changes the number of active subscriptions to the channel or pattern by delta.
+func (o *Observer) ObserveSubscriptions(channel string, delta int) {
+	if o.pubsub == nil {
+		return
+	}
+
+	o.pubsub.CollectSubscriptions(metrics.RedisPubSubProperties{Channel: o.channels.resolve(channel)}, delta)
+}
+
+// ObservePubSubReconnect records the reconnect of Pub/Sub subscription.
+func (o *Observer) ObservePubSubReconnect() {
+	if o.pubsub == nil {
+		return
+	}
+
+	o.pubsub.CollectPubSubReconnect()
+}
+
+// observePublish records the message published by PUBLISH or SPUBLISH command.
+func (o *Observer) observePublish(cmd Cmder) {
+	if name := cmd.Name(); o.pubsub == nil || name != "publish" && name != "spublish" || len(cmd.Args()) < 2 {
+		return
+	}
+
+	o.pubsub.CollectPublish(metrics.RedisPubSubProperties{
+		Channel: o.channels.resolve(argString(cmd.Args()[1])),
+		Code:    o.classifyError(cmd.Err()),
+	})
+}
+
+// observeTopology records redirects and reloads of cluster slots replied by the node.
+func (o *Observer) observeTopology(cmd Cmder) {
+	if o.topology == nil || o.node == "" {
+		return
+	}
+
+	var event string
+	switch status := ClassifyError(cmd.Err()); {
+	case status == statusMoved || status == statusAsk:
+		event = status
+	case cmd.Name() == "cluster" && len(cmd.Args()) >= 2 && strings.EqualFold(argString(cmd.Args()[1]), "slots"):
+		event = eventSlotsReload
+	default:
+		return
+	}
+
+	o.topology.CollectTopology(metrics.RedisTopologyProperties{Node: o.node, Event: event})
+}
+
+// properties returns properties of the processed command.
+func (o *Observer) properties(cmd Cmder) metrics.RedisReqProperties {
+	hits, misses := lookups(cmd)
+	script := o.scripts.resolve(cmd.Args())
+
+	return metrics.RedisReqProperties{
+		Command:  cmd.Name(),
+		Keyspace: o.keyspaces.resolve(cmd.Args()),
+		Code:     o.classifyError(cmd.Err()),
+		Node:     o.node,
+		Hits:     hits,
+		Misses:   misses,
+		Script:   script,
+		NoScript: script != "" && ClassifyError(cmd.Err()) == statusNoScript,
+
+		RequestSize: requestSize(cmd),
+		ReplySize:   replySize(cmd),
+	}
+}
@@ -0,0 +1,90 @@
+package redis_test
+
+import (
+	"errors"
+	"github.com/go-redis/redis/v7"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/weaponry/go-instrumenting/metrics"
+	"github.com/weaponry/go-instrumenting/metrics/metricstest"
+	redismetrics "github.com/weaponry/go-instrumenting/metrics/redis"
+	"testing"
+	"time"
+)
+
+func TestObserver(t *testing.T) {
+	recorder := metricstest.NewRedisRecorder()
+	observer := redismetrics.NewObserver(recorder)
+
+	cmd := redis.NewStringCmd("get", "app/users/1")
+	cmd.SetErr(redisError("redis: nil"))
+	observer.ObserveCommand(cmd, time.Millisecond)
+
+	observer.ObserveDial("10.0.0.1:6379", nil, time.Millisecond)
+	observer.ObserveDial("10.0.0.1:6379", errors.New("dial failed"), time.Millisecond)
+
+	calls := recorder.Calls()
+	require.Len(t, calls, 1)
+	assert.Equal(t, "/users", calls[0].Props.Keyspace)
+	assert.Equal(t, "nil", calls[0].Props.Code)
+	assert.Equal(t, 1, calls[0].Props.Misses)
+
+	dialCalls := recorder.DialCalls()
+	require.Len(t, dialCalls, 2)
+	assert.Equal(t, metrics.RedisDialProperties{Node: "10.0.0.1:6379", Code: "ok"}, dialCalls[0].Props)
+	assert.Equal(t, metrics.RedisDialProperties{Node: "10.0.0.1:6379", Code: "other"}, dialCalls[1].Props)
+}
+
+func TestObserverConfig(t *testing.T) {
+	registry := prometheus.NewRegistry()
+	recorder := redismetrics.NewRedisRecorder("test-app", redismetrics.Config{
+		Options:           metrics.Options{Registerer: registry},
+		ErrorClassifier:   func(err error) string { return "classified" },
+		KeyspaceExtractor: redismetrics.NewDelimiterExtractor("/", 2),
+	})
+
+	// Observer should use configuration of recorders created by the package.
+	cmd := redis.NewStringCmd("get", "app/users/1")
+	redismetrics.NewObserver(recorder).ObserveCommand(cmd, time.Millisecond)
+	redismetrics.NewObserver(recorder).ObserveDial("10.0.0.1:6379", nil, time.Millisecond)
+
+	metricstest.AssertCounter(t, registry, "app_redis_requests_total", prometheus.Labels{"keyspace": "app/users", "status": "classified"}, 1)
+	metricstest.AssertCounter(t, registry, "app_redis_dials_total", prometheus.Labels{"node": "10.0.0.1:6379", "status": "classified"}, 1)
+	metricstest.AssertHistogramCount(t, registry, "app_redis_dial_duration_seconds", nil, 1)
+}
+
+// baseRedisRecorder implements only the required methods of metrics.RedisRecorder.
+type baseRedisRecorder struct {
+	calls []metrics.RedisReqProperties
+}
+
+func (r *baseRedisRecorder) NewCollectHook() redis.Hook { return redismetrics.NewCollectHook(r) }
+
+func (r *baseRedisRecorder) Collect(props metrics.RedisReqProperties, _ time.Duration) {
+	r.calls = append(r.calls, props)
+}
+
+func (r *baseRedisRecorder) Unregister() {}
+
+func TestObserverBaseRecorder(t *testing.T) {
+	recorder := &baseRedisRecorder{}
+	observer := redismetrics.NewObserver(recorder).ForNode("10.0.0.1:6379")
+
+	// Commands of pipelines should be passed to Collect of recorders which don't record pipelines.
+	observer.ObservePipeline([]redismetrics.Cmder{
+		redis.NewStringCmd("get", "app/users/1"),
+		redis.NewIntCmd("publish", "cache/users", "1"),
+	}, time.Millisecond)
+
+	// Events not supported by the recorder should be ignored.
+	observer.ObserveDial("10.0.0.1:6379", nil, time.Millisecond)
+	observer.ObserveSwitchMaster("", "mymaster 10.0.0.1 6379 10.0.0.2 6379")
+	observer.ObserveMessage("cache/users", "", time.Millisecond)
+	observer.ObserveSubscriptions("cache/users", 1)
+	observer.ObservePubSubReconnect()
+
+	require.Len(t, recorder.calls, 2)
+	assert.Equal(t, "get", recorder.calls[0].Command)
+	assert.Equal(t, "publish", recorder.calls[1].Command)
+}
@@ -0,0 +1,110 @@
+package redis
+
+import (
+	"fmt"
+	"github.com/go-redis/redis/v7"
+	"github.com/prometheus/client_golang/prometheus"
+	"sync"
+)
+
+const labelNode = "node"
+
+// poolStatsCollector exports connection pool statistics of Redis clients at scrape time.
+type poolStatsCollector struct {
+	forEachNode func(fn func(client *redis.Client) error) error
+
+	hits       *prometheus.Desc
+	misses     *prometheus.Desc
+	timeouts   *prometheus.Desc
+	staleConns *prometheus.Desc
+	totalConns *prometheus.Desc
+	idleConns  *prometheus.Desc
+}
+
+// NewPoolStatsCollector creates collector of pool statistics and registers it, it panics if registration fails.
+func NewPoolStatsCollector(appName string, client redis.UniversalClient, config Config) prometheus.Collector {
+	c, err := RegisterPoolStatsCollector(appName, client, config)
+	if err != nil {
+		panic(err)
+	}
+	return c
+}
+
+// RegisterPoolStatsCollector creates collector of pool statistics and registers it. Client could be *redis.Client,
+// *redis.ClusterClient or *redis.Ring, statistics are labelled by address of the node (shard of the ring). Note that
+// collecting statistics of cluster nodes reloads cluster state, and shards of the ring which are down are skipped.
+// Use constant labels of config for distinguishing several clients of the application. Collector could be removed
+// with Unregister of config's Registerer.
+func RegisterPoolStatsCollector(appName string, client redis.UniversalClient, config Config) (prometheus.Collector, error) {
+	config.defaults()
+
+	if err := config.Validate(labelNode); err != nil {
+		return nil, err
+	}
+
+	c := &poolStatsCollector{}
+
+	switch v := client.(type) {
+	case *redis.Client:
+		c.forEachNode = func(fn func(client *redis.Client) error) error { return fn(v) }
+	case *redis.ClusterClient:
+		c.forEachNode = v.ForEachNode
+	case *redis.Ring:
+		c.forEachNode = v.ForEachShard
+	default:
+		return nil, fmt.Errorf("unsupported client type %T", client)
+	}
+
+	constLabels := config.Labels(appName)
+	desc := func(name, help string) *prometheus.Desc {
+		return prometheus.NewDesc(prometheus.BuildFQName(config.Namespace, config.Subsystem, name), help, []string{labelNode}, constLabels)
+	}
+
+	c.hits = desc("pool_hits_total", "The total number of times free connection was found in the pool.")
+	c.misses = desc("pool_misses_total", "The total number of times free connection was not found in the pool.")
+	c.timeouts = desc("pool_timeouts_total", "The total number of times a wait timeout occurred.")
+	c.staleConns = desc("pool_stale_connections_total", "The total number of stale connections removed from the pool.")
+	c.totalConns = desc("pool_total_connections", "The number of total connections in the pool.")
+	c.idleConns = desc("pool_idle_connections", "The number of idle connections in the pool.")
+
+	// Collectors of different clients can't be shared, hence AlreadyRegisteredError is returned as is.
+	if err := config.Registerer.Register(c); err != nil {
+		return nil, err
+	}
+
+	return c, nil
+}
+
+// Describe implements prometheus.Collector.
+func (c *poolStatsCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.hits
+	ch <- c.misses
+	ch <- c.timeouts
+	ch <- c.staleConns
+	ch <- c.totalConns
+	ch <- c.idleConns
+}
+
+// Collect implements prometheus.Collector.
+func (c *poolStatsCollector) Collect(ch chan<- prometheus.Metric) {
+	// Nodes are iterated concurrently by cluster and ring clients.
+	var mu sync.Mutex
+
+	_ = c.forEachNode(func(client *redis.Client) error {
+		var (
+			stats = client.PoolStats()
+			node  = client.Options().Addr
+		)
+
+		mu.Lock()
+		defer mu.Unlock()
+
+		ch <- prometheus.MustNewConstMetric(c.hits, prometheus.CounterValue, float64(stats.Hits), node)
+		ch <- prometheus.MustNewConstMetric(c.misses, prometheus.CounterValue, float64(stats.Misses), node)
+		ch <- prometheus.MustNewConstMetric(c.timeouts, prometheus.CounterValue, float64(stats.Timeouts), node)
+		ch <- prometheus.MustNewConstMetric(c.staleConns, prometheus.CounterValue, float64(stats.StaleConns), node)
+		ch <- prometheus.MustNewConstMetric(c.totalConns, prometheus.GaugeValue, float64(stats.TotalConns), node)
+		ch <- prometheus.MustNewConstMetric(c.idleConns, prometheus.GaugeValue, float64(stats.IdleConns), node)
+		return nil
+	})
+}
@@ -0,0 +1,123 @@
+package redis_test
+
+import (
+	"github.com/go-redis/redis/v7"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/weaponry/go-instrumenting/metrics"
+	"github.com/weaponry/go-instrumenting/metrics/metricstest"
+	redismetrics "github.com/weaponry/go-instrumenting/metrics/redis"
+	"io/ioutil"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestRegisterPoolStatsCollector(t *testing.T) {
+	client := redis.NewClient(&redis.Options{Addr: "127.0.0.1:1", MaxRetries: -1})
+	defer func() { _ = client.Close() }()
+
+	ring := redis.NewRing(&redis.RingOptions{
+		Addrs:      map[string]string{"shard1": "127.0.0.1:2", "shard2": "127.0.0.1:3"},
+		MaxRetries: -1,
+	})
+	defer func() { _ = ring.Close() }()
+
+	// Produce pool misses.
+	_ = client.Ping().Err()
+
+	registry := prometheus.NewRegistry()
+
+	_, err := redismetrics.RegisterPoolStatsCollector("test-app", client, redismetrics.Config{
+		Options: metrics.Options{ConstLabels: prometheus.Labels{"client": "cache"}, Registerer: registry},
+	})
+	require.NoError(t, err)
+
+	_, err = redismetrics.RegisterPoolStatsCollector("test-app", ring, redismetrics.Config{
+		Options: metrics.Options{ConstLabels: prometheus.Labels{"client": "ring"}, Registerer: registry},
+	})
+	require.NoError(t, err)
+
+	// Collector of another client with the same labels should not be registered.
+	_, err = redismetrics.RegisterPoolStatsCollector("test-app", ring, redismetrics.Config{
+		Options: metrics.Options{ConstLabels: prometheus.Labels{"client": "ring"}, Registerer: registry},
+	})
+	assert.Error(t, err)
+
+	// Constant labels should not collide with the node label.
+	_, err = redismetrics.RegisterPoolStatsCollector("test-app", client, redismetrics.Config{
+		Options: metrics.Options{ConstLabels: prometheus.Labels{"node": "primary"}, Registerer: prometheus.NewRegistry()},
+	})
+	assert.Error(t, err)
+
+	// Get the metrics handler and serve.
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest("GET", "/metrics", nil)
+	promhttp.HandlerFor(registry, promhttp.HandlerOpts{}).ServeHTTP(rec, req)
+
+	body, _ := ioutil.ReadAll(rec.Result().Body)
+	for _, expMetric := range []string{
+		`app_redis_pool_hits_total{application="test-app",client="cache",node="127.0.0.1:1"} 0`,
+		`app_redis_pool_misses_total{application="test-app",client="cache",node="127.0.0.1:1"} 1`,
+		`app_redis_pool_timeouts_total{application="test-app",client="cache",node="127.0.0.1:1"} 0`,
+		`app_redis_pool_stale_connections_total{application="test-app",client="cache",node="127.0.0.1:1"} 0`,
+		`app_redis_pool_total_connections{application="test-app",client="cache",node="127.0.0.1:1"} 0`,
+		`app_redis_pool_idle_connections{application="test-app",client="cache",node="127.0.0.1:1"} 0`,
+		`app_redis_pool_total_connections{application="test-app",client="ring",node="127.0.0.1:2"} 0`,
+		`app_redis_pool_total_connections{application="test-app",client="ring",node="127.0.0.1:3"} 0`,
+	} {
+		assert.Contains(t, string(body), expMetric, "metric not present on the result")
+	}
+}
+
+func TestPoolStatsCollectorCluster(t *testing.T) {
+	cluster := redis.NewClusterClient(&redis.ClusterOptions{
+		ClusterSlots: func() ([]redis.ClusterSlot, error) {
+			return []redis.ClusterSlot{
+				{Start: 0, End: 8191, Nodes: []redis.ClusterNode{{Addr: "10.0.0.1:6379"}}},
+				{Start: 8192, End: 16383, Nodes: []redis.ClusterNode{{Addr: "10.0.0.2:6379"}}},
+			}, nil
+		},
+		Dialer: pongDialer(),
+	})
+	defer func() { _ = cluster.Close() }()
+
+	require.NoError(t, cluster.ForEachNode(func(client *redis.Client) error { return client.Ping().Err() }))
+
+	registry := prometheus.NewRegistry()
+	_, err := redismetrics.RegisterPoolStatsCollector("test-app", cluster, redismetrics.Config{Options: metrics.Options{Registerer: registry}})
+	require.NoError(t, err)
+
+	// Statistics should be labelled by nodes of the cluster.
+	assert.Equal(t, []string{"10.0.0.1:6379", "10.0.0.2:6379"}, metricstest.LabelValues(t, registry, "app_redis_pool_total_connections", "node"))
+	for _, node := range []string{"10.0.0.1:6379", "10.0.0.2:6379"} {
+		metricstest.AssertCounter(t, registry, "app_redis_pool_misses_total", prometheus.Labels{"node": node}, 1)
+		metricstest.AssertGauge(t, registry, "app_redis_pool_total_connections", prometheus.Labels{"node": node}, 1)
+		metricstest.AssertGauge(t, registry, "app_redis_pool_idle_connections", prometheus.Labels{"node": node}, 1)
+	}
+}
+
+func TestPoolStatsCollectorRing(t *testing.T) {
+	dialer := pongDialer()
+	ring := redis.NewRing(&redis.RingOptions{
+		Addrs: map[string]string{"shard1": "10.0.0.1:6379", "shard2": "10.0.0.2:6379"},
+		NewClient: func(_ string, opt *redis.Options) *redis.Client {
+			opt.Dialer = dialer
+			return redis.NewClient(opt)
+		},
+	})
+	defer func() { _ = ring.Close() }()
+
+	require.NoError(t, ring.ForEachShard(func(client *redis.Client) error { return client.Ping().Err() }))
+
+	registry := prometheus.NewRegistry()
+	_, err := redismetrics.RegisterPoolStatsCollector("test-app", ring, redismetrics.Config{Options: metrics.Options{Registerer: registry}})
+	require.NoError(t, err)
+
+	// Statistics should be labelled by addresses of shards.
+	assert.Equal(t, []string{"10.0.0.1:6379", "10.0.0.2:6379"}, metricstest.LabelValues(t, registry, "app_redis_pool_total_connections", "node"))
+	for _, node := range []string{"10.0.0.1:6379", "10.0.0.2:6379"} {
+		metricstest.AssertGauge(t, registry, "app_redis_pool_total_connections", prometheus.Labels{"node": node}, 1)
+	}
+}
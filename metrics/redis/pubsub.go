@@ -0,0 +1,272 @@
+package redis
+
+import (
+	"fmt"
+	"github.com/go-redis/redis/v7"
+	"github.com/weaponry/go-instrumenting/metrics"
+	"sync"
+	"time"
+)
+
+// PubSub is a *redis.PubSub which passes properties of received messages, active subscriptions and reconnects to
+// recorder, messages published by clients are recorded by their hooks. Subscriptions are counted when they are
+// confirmed by the server, go-redis resubscribes after reconnects, hence repeated confirmations are counted as
+// reconnects. Messages should be received by methods of the wrapper, not of the wrapped *redis.PubSub.
+type PubSub struct {
+	*redis.PubSub
+
+	observer *Observer
+
+	mu      sync.Mutex
+	closed  bool
+	active  map[subscription]bool
+	pending map[subscription]bool
+
+	chOnce sync.Once
+	chSize int
+	msgCh  chan *redis.Message
+	allCh  chan interface{}
+}
+
+// subscription is a subscription to the channel or the pattern.
+type subscription struct {
+	channel string
+	pattern bool
+}
+
+// received is a message received from the connection, which waits for the receiver.
+type received struct {
+	msg interface{}
+	at  time.Time
+}
+
+// NewPubSub returns wrapper of pubsub passing its properties to recorder, e.g.
+// NewPubSub(client.Subscribe(), recorder). Subscriptions made before wrapping are counted when their confirmations are
+// received.
+func NewPubSub(pubsub *redis.PubSub, recorder metrics.RedisRecorder) *PubSub {
+	return &PubSub{
+		PubSub:   pubsub,
+		observer: NewObserver(recorder),
+		active:   make(map[subscription]bool),
+		pending:  make(map[subscription]bool),
+	}
+}
+
+// Subscribe subscribes to the channels.
+func (p *PubSub) Subscribe(channels ...string) error {
+	p.expect(channels, false)
+	return p.PubSub.Subscribe(channels...)
+}
+
+// PSubscribe subscribes to the patterns.
+func (p *PubSub) PSubscribe(patterns ...string) error {
+	p.expect(patterns, true)
+	return p.PubSub.PSubscribe(patterns...)
+}
+
+// Close closes the subscription, active subscriptions are not counted anymore.
+func (p *PubSub) Close() error {
+	p.mu.Lock()
+	for s := range p.active {
+		p.observer.ObserveSubscriptions(s.channel, -1)
+	}
+	p.closed = true
+	p.active = nil
+	p.pending = nil
+	p.mu.Unlock()
+
+	return p.PubSub.Close()
+}
+
+// Receive returns a message as a Subscription, Message, Pong or error, see redis.PubSub.Receive.
+func (p *PubSub) Receive() (interface{}, error) {
+	return p.ReceiveTimeout(0)
+}
+
+// ReceiveTimeout is like Receive, but fails with timeout, see redis.PubSub.ReceiveTimeout.
+func (p *PubSub) ReceiveTimeout(timeout time.Duration) (interface{}, error) {
+	msg, err := p.PubSub.ReceiveTimeout(timeout)
+	if err != nil {
+		return nil, err
+	}
+
+	switch msg := msg.(type) {
+	case *redis.Subscription:
+		p.observeSubscription(msg)
+	case *redis.Message:
+		p.observer.ObserveMessage(msg.Channel, msg.Pattern, -1)
+	}
+
+	return msg, nil
+}
+
+// ReceiveMessage returns a Message or error ignoring Subscription and Pong messages, see
+// redis.PubSub.ReceiveMessage.
+func (p *PubSub) ReceiveMessage() (*redis.Message, error) {
+	for {
+		msg, err := p.Receive()
+		if err != nil {
+			return nil, err
+		}
+
+		switch msg := msg.(type) {
+		case *redis.Subscription:
+			// Ignore.
+		case *redis.Pong:
+			// Ignore.
+		case *redis.Message:
+			return msg, nil
+		default:
+			return nil, fmt.Errorf("redis: unknown message: %T", msg)
+		}
+	}
+}
+
+// Channel returns a Go channel for concurrently receiving messages, see redis.PubSub.Channel. Up to 100 messages
+// wait for the receiver, the time they wait is measured as the lag.
+func (p *PubSub) Channel() <-chan *redis.Message {
+	return p.ChannelSize(100)
+}
+
+// ChannelSize is like Channel, but up to size messages wait for the receiver.
+func (p *PubSub) ChannelSize(size int) <-chan *redis.Message {
+	p.chOnce.Do(func() {
+		p.chSize = size
+		p.msgCh = make(chan *redis.Message)
+		go p.forward(p.PubSub.ChannelWithSubscriptions(1), size)
+	})
+	if p.msgCh == nil {
+		panic(fmt.Errorf("redis: Channel can't be called after ChannelWithSubscriptions"))
+	}
+	if p.chSize != size {
+		panic(fmt.Errorf("redis: PubSub.Channel size can not be changed once created"))
+	}
+	return p.msgCh
+}
+
+// ChannelWithSubscriptions is like ChannelSize, but message type can be either *Subscription or *Message, see
+// redis.PubSub.ChannelWithSubscriptions.
+func (p *PubSub) ChannelWithSubscriptions(size int) <-chan interface{} {
+	p.chOnce.Do(func() {
+		p.chSize = size
+		p.allCh = make(chan interface{})
+		go p.forward(p.PubSub.ChannelWithSubscriptions(1), size)
+	})
+	if p.allCh == nil {
+		panic(fmt.Errorf("redis: ChannelWithSubscriptions can't be called after Channel"))
+	}
+	if p.chSize != size {
+		panic(fmt.Errorf("redis: PubSub.Channel size can not be changed once created"))
+	}
+	return p.allCh
+}
+
+// forward passes messages received from in to the channel of the wrapper. Up to size messages are queued, the
+// channel of the wrapper is unbuffered, hence the lag of the message is measured when the receiver takes it.
+func (p *PubSub) forward(in <-chan interface{}, size int) {
+	defer func() {
+		if p.msgCh != nil {
+			close(p.msgCh)
+		} else {
+			close(p.allCh)
+		}
+	}()
+
+	if size < 1 {
+		size = 1
+	}
+
+	var queue []received
+	for {
+		var (
+			recv  = in
+			msgCh chan<- *redis.Message
+			allCh chan<- interface{}
+			next  received
+		)
+		if len(queue) >= size {
+			recv = nil
+		}
+		if len(queue) > 0 {
+			next = queue[0]
+			if p.msgCh != nil {
+				msgCh = p.msgCh
+			} else {
+				allCh = p.allCh
+			}
+		}
+		msg, _ := next.msg.(*redis.Message)
+
+		select {
+		case v, ok := <-recv:
+			if !ok {
+				return
+			}
+			if s, ok := v.(*redis.Subscription); ok {
+				p.observeSubscription(s)
+				if p.allCh == nil {
+					continue
+				}
+			}
+			queue = append(queue, received{msg: v, at: time.Now()})
+		case msgCh <- msg:
+			p.observer.ObserveMessage(msg.Channel, msg.Pattern, time.Since(next.at))
+			queue = queue[1:]
+		case allCh <- next.msg:
+			if msg != nil {
+				p.observer.ObserveMessage(msg.Channel, msg.Pattern, time.Since(next.at))
+			}
+			queue = queue[1:]
+		}
+	}
+}
+
+// expect marks active subscriptions which are requested again, hence their confirmations are not reconnects.
+func (p *PubSub) expect(channels []string, pattern bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	for _, channel := range channels {
+		s := subscription{channel: channel, pattern: pattern}
+		if p.active[s] {
+			p.pending[s] = true
+		}
+	}
+}
+
+// observeSubscription updates active subscriptions using the confirmation received from the server.
+func (p *PubSub) observeSubscription(msg *redis.Subscription) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.closed {
+		return
+	}
+
+	s := subscription{channel: msg.Channel, pattern: msg.Kind == "psubscribe" || msg.Kind == "punsubscribe"}
+	switch msg.Kind {
+	case "subscribe", "psubscribe":
+		switch {
+		case !p.active[s]:
+			p.active[s] = true
+			p.observer.ObserveSubscriptions(s.channel, 1)
+		case p.pending[s]:
+			delete(p.pending, s)
+		default:
+			// Confirmations of all other active subscriptions are expected after the reconnect.
+			p.observer.ObservePubSubReconnect()
+			p.pending = make(map[subscription]bool, len(p.active))
+			for active := range p.active {
+				if active != s {
+					p.pending[active] = true
+				}
+			}
+		}
+	case "unsubscribe", "punsubscribe":
+		if p.active[s] {
+			delete(p.active, s)
+			delete(p.pending, s)
+			p.observer.ObserveSubscriptions(s.channel, -1)
+		}
+	}
+}
@@ -0,0 +1,267 @@
+package redis_test
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"github.com/go-redis/redis/v7"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/weaponry/go-instrumenting/metrics"
+	"github.com/weaponry/go-instrumenting/metrics/metricstest"
+	redismetrics "github.com/weaponry/go-instrumenting/metrics/redis"
+	"net"
+	"path"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakePubSub is an in-memory Redis server of Pub/Sub connections.
+type fakePubSub struct {
+	mu    sync.Mutex
+	conns map[net.Conn]*fakeSubscriber
+}
+
+// fakeSubscriber is a connection of subscriber, replies are written by a separate goroutine, hence the server never
+// waits for the client.
+type fakeSubscriber struct {
+	out      chan string
+	channels map[string]bool
+	patterns map[string]bool
+}
+
+func newFakePubSub() *fakePubSub {
+	return &fakePubSub{conns: make(map[net.Conn]*fakeSubscriber)}
+}
+
+func (s *fakePubSub) dial(context.Context, string, string) (net.Conn, error) {
+	clientConn, serverConn := net.Pipe()
+	sub := &fakeSubscriber{
+		out:      make(chan string, 100),
+		channels: make(map[string]bool),
+		patterns: make(map[string]bool),
+	}
+
+	s.mu.Lock()
+	s.conns[serverConn] = sub
+	s.mu.Unlock()
+
+	go s.serve(serverConn, sub)
+	return clientConn, nil
+}
+
+func (s *fakePubSub) serve(conn net.Conn, sub *fakeSubscriber) {
+	done := make(chan struct{})
+	defer func() {
+		close(done)
+		_ = conn.Close()
+
+		s.mu.Lock()
+		delete(s.conns, conn)
+		s.mu.Unlock()
+	}()
+
+	go func() {
+		for {
+			select {
+			case reply := <-sub.out:
+				if _, err := conn.Write([]byte(reply)); err != nil {
+					return
+				}
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	r := bufio.NewReader(conn)
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return
+		}
+		n, _ := strconv.Atoi(strings.TrimSpace(line[1:]))
+
+		args := make([]string, 0, n)
+		for i := 0; i < n; i++ {
+			if _, err := r.ReadString('\n'); err != nil {
+				return
+			}
+			arg, err := r.ReadString('\n')
+			if err != nil {
+				return
+			}
+			args = append(args, strings.TrimSuffix(arg, "\r\n"))
+		}
+
+		s.mu.Lock()
+		kind := strings.ToLower(args[0])
+		switch kind {
+		case "subscribe", "psubscribe", "unsubscribe", "punsubscribe":
+			subscribed := sub.channels
+			if kind[0] == 'p' {
+				subscribed = sub.patterns
+			}
+			for _, channel := range args[1:] {
+				subscribed[channel] = kind == "subscribe" || kind == "psubscribe"
+				if !subscribed[channel] {
+					delete(subscribed, channel)
+				}
+				sub.out <- "*3\r\n" + bulk(kind) + bulk(channel) + fmt.Sprintf(":%d\r\n", len(sub.channels)+len(sub.patterns))
+			}
+		case "ping":
+			sub.out <- "*2\r\n" + bulk("pong") + bulk("")
+		default:
+			sub.out <- "-ERR unknown command\r\n"
+		}
+		s.mu.Unlock()
+	}
+}
+
+// publish sends the message to subscribers of the channel and returns the number of receivers.
+func (s *fakePubSub) publish(channel, payload string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var receivers int
+	for _, sub := range s.conns {
+		if sub.channels[channel] {
+			sub.out <- "*3\r\n" + bulk("message") + bulk(channel) + bulk(payload)
+			receivers++
+		}
+		for pattern := range sub.patterns {
+			if ok, _ := path.Match(pattern, channel); ok {
+				sub.out <- "*4\r\n" + bulk("pmessage") + bulk(pattern) + bulk(channel) + bulk(payload)
+				receivers++
+			}
+		}
+	}
+
+	return receivers
+}
+
+// disconnect closes all connections.
+func (s *fakePubSub) disconnect() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for conn := range s.conns {
+		_ = conn.Close()
+	}
+}
+
+func bulk(v string) string {
+	return fmt.Sprintf("$%d\r\n%s\r\n", len(v), v)
+}
+
+func TestPubSubChannel(t *testing.T) {
+	registry := prometheus.NewRegistry()
+	recorder := redismetrics.NewRedisRecorder("test-app", redismetrics.Config{
+		Options:  metrics.Options{Registerer: registry},
+		Channels: []string{"cache/users", "events/*"},
+	})
+
+	server := newFakePubSub()
+	client := redis.NewClient(&redis.Options{Dialer: server.dial})
+	defer func() { _ = client.Close() }()
+
+	pubsub := redismetrics.NewPubSub(client.Subscribe(), recorder)
+	require.NoError(t, pubsub.Subscribe("cache/users"))
+	require.NoError(t, pubsub.PSubscribe("events/*"))
+	ch := pubsub.Channel()
+
+	subscriptions := func(channel string) float64 {
+		return metricstest.GaugeValue(t, registry, "app_redis_pubsub_subscriptions", prometheus.Labels{"channel": channel})
+	}
+
+	// Subscriptions should be counted when they are confirmed.
+	require.Eventually(t, func() bool {
+		return subscriptions("cache/users") == 1 && subscriptions("events/*") == 1
+	}, time.Second, 10*time.Millisecond)
+
+	// Messages should be counted by channels and patterns of subscriptions.
+	server.publish("cache/users", "1")
+	server.publish("events/orders", "2")
+	assert.Equal(t, "1", (<-ch).Payload)
+	assert.Equal(t, "2", (<-ch).Payload)
+	require.Eventually(t, func() bool {
+		return metricstest.HistogramCount(t, registry, "app_redis_pubsub_receive_lag_seconds", nil) == 2
+	}, time.Second, 10*time.Millisecond)
+	metricstest.AssertCounter(t, registry, "app_redis_pubsub_received_messages_total", prometheus.Labels{"channel": "cache/users"}, 1)
+	metricstest.AssertCounter(t, registry, "app_redis_pubsub_received_messages_total", prometheus.Labels{"channel": "events/*"}, 1)
+
+	// Resubscriptions after reconnects should not be counted as new subscriptions.
+	server.disconnect()
+	require.Eventually(t, func() bool {
+		return metricstest.CounterValue(t, registry, "app_redis_pubsub_reconnects_total", nil) == 1
+	}, time.Second, 10*time.Millisecond)
+	assert.Equal(t, float64(1), subscriptions("cache/users"))
+	assert.Equal(t, float64(1), subscriptions("events/*"))
+
+	require.NoError(t, pubsub.Unsubscribe("cache/users"))
+	require.Eventually(t, func() bool {
+		return subscriptions("cache/users") == 0
+	}, time.Second, 10*time.Millisecond)
+
+	require.NoError(t, pubsub.Close())
+	assert.Equal(t, float64(0), subscriptions("events/*"))
+	_, ok := <-ch
+	assert.False(t, ok)
+}
+
+func TestPubSubReceiveMessage(t *testing.T) {
+	recorder := metricstest.NewRedisRecorder()
+
+	server := newFakePubSub()
+	client := redis.NewClient(&redis.Options{Dialer: server.dial})
+	defer func() { _ = client.Close() }()
+
+	// Subscriptions made before wrapping should be counted too.
+	pubsub := redismetrics.NewPubSub(client.Subscribe("cache/users"), recorder)
+	defer func() { _ = pubsub.Close() }()
+
+	receive := func() {
+		require.Eventually(t, func() bool {
+			return server.publish("cache/users", "1") == 1
+		}, time.Second, 10*time.Millisecond)
+
+		msg, err := pubsub.ReceiveMessage()
+		require.NoError(t, err)
+		assert.Equal(t, "1", msg.Payload)
+	}
+
+	// Channels are not known to recorders created by other packages.
+	receive()
+	assert.Equal(t, map[string]int{"other": 1}, recorder.Subscriptions())
+	assert.Equal(t, []metricstest.RedisReceiveCall{
+		{Props: metrics.RedisPubSubProperties{Channel: "other"}, Lag: -1},
+	}, recorder.ReceiveCalls())
+
+	// Repeated subscriptions should not be counted as reconnects.
+	require.NoError(t, pubsub.Subscribe("cache/users"))
+	receive()
+	assert.Equal(t, map[string]int{"other": 1}, recorder.Subscriptions())
+	assert.Zero(t, recorder.PubSubReconnects())
+}
+
+func TestObserverPublish(t *testing.T) {
+	recorder := metricstest.NewRedisRecorder()
+	observer := redismetrics.NewObserver(recorder)
+
+	published := redis.NewIntCmd("publish", "cache/users", "1")
+	failed := redis.NewIntCmd("publish", "cache/orders", "1")
+	failed.SetErr(redisError("READONLY You can't write against a read only replica."))
+
+	observer.ObserveCommand(published, time.Millisecond)
+	observer.ObservePipeline([]redismetrics.Cmder{failed, redis.NewStringCmd("get", "app/users/1")}, time.Millisecond)
+
+	// Channels are not known to recorders created by other packages.
+	assert.Equal(t, []metrics.RedisPubSubProperties{
+		{Channel: "other", Code: "ok"},
+		{Channel: "other", Code: "readonly"},
+	}, recorder.Published())
+}
@@ -5,16 +5,21 @@ import (
 	"github.com/go-redis/redis/v7"
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/weaponry/go-instrumenting/metrics"
-	"regexp"
-	"strings"
 	"time"
 )
 
 const (
-	labelApp      = "application"
 	labelStatus   = "status"
 	labelCommand  = "command"
 	labelKeyspace = "keyspace"
+	labelPipeline = "pipeline"
+	labelEvent    = "event"
+	labelChannel  = "channel"
+	labelScript   = "script"
+
+	pipelineTypeNone     = "none"
+	pipelineTypePipeline = "pipeline"
+	pipelineTypeTx       = "tx"
 
 	keyRequestStart key = iota
 )
@@ -22,53 +27,330 @@ const (
 type key int
 
 type Config struct {
+	// Options describe names of metrics, constant labels and the registerer, by default metrics are named as
+	// 'app_<subsystem>_*' and registered with prometheus.DefaultRegisterer.
+	metrics.Options
 	// DurationBuckets are the buckets used by Prometheus for the HTTP request duration metrics,
 	// by default uses Prometheus default buckets (from 5ms to 10s).
 	DurationBuckets []float64
+	// PipelineSizeBuckets are the buckets used by Prometheus for the number of commands in pipelines,
+	// by default uses a exponential buckets from 1 to 512.
+	PipelineSizeBuckets []float64
+	// SizeBuckets are the buckets used by Prometheus for the request and reply size metrics,
+	// by default uses a exponential buckets from 16B to 4MB.
+	SizeBuckets []float64
+	// ErrorClassifier maps errors of commands to values of the status label, by default uses ClassifyError.
+	// Returned values should be bounded, nil error should be mapped to 'ok'.
+	ErrorClassifier func(err error) string
+	// KeyspaceExtractor extracts keyspaces of keys used by commands, by default uses DefaultKeyspaceExtractor.
+	// Commands with keys of different keyspaces are labelled as 'mixed'.
+	KeyspaceExtractor KeyspaceExtractor
+	// Keyspaces are known keyspaces, other extracted keyspaces are labelled as 'other'. By default all extracted
+	// keyspaces are used as is.
+	Keyspaces []string
+	// NodeLabel adds the node label with address of the node which served requests, it is set only by hooks of nodes
+	// of cluster clients (see NewClusterNodeClientFunc). By default requests are not labelled by nodes.
+	NodeLabel bool
+	// Scripts are Lua scripts by their names, executions of scripts by EVAL and EVALSHA are labelled by names,
+	// executions of other scripts are labelled as 'other'. More scripts could be named with RegisterScript.
+	Scripts map[string]Script
+	// ServerStatsMaxAge is the maximum age of results of INFO and SLOWLOG GET commands reused by scrapes of server
+	// statistics collector, by default 10s.
+	ServerStatsMaxAge time.Duration
+	// ServerStatsTimeout is the timeout of INFO and SLOWLOG GET commands sent by server statistics collector, scrapes
+	// wait for the commands at most this time and export the latest results otherwise. By default 5s.
+	ServerStatsTimeout time.Duration
+	// SlowlogEntries is the number of the latest slowlog entries requested by server statistics collector, entries
+	// logged between scrapes over this number are not counted. By default 128 (the default slowlog-max-len).
+	SlowlogEntries int
+	// Channels are glob-style patterns of known Pub/Sub channels (as patterns of PSUBSCRIBE), channels are labelled by
+	// the first matching pattern and other channels are labelled as 'other'. By default all channels are 'other'.
+	Channels []string
 }
 
 func (c *Config) defaults() {
+	c.SetDefaults("redis")
+
 	if len(c.DurationBuckets) == 0 {
 		c.DurationBuckets = prometheus.DefBuckets
 	}
+
+	if len(c.PipelineSizeBuckets) == 0 {
+		c.PipelineSizeBuckets = prometheus.ExponentialBuckets(1, 2, 10)
+	}
+
+	if len(c.SizeBuckets) == 0 {
+		c.SizeBuckets = prometheus.ExponentialBuckets(16, 4, 10)
+	}
+
+	if c.ServerStatsMaxAge == 0 {
+		c.ServerStatsMaxAge = 10 * time.Second
+	}
+
+	if c.ServerStatsTimeout == 0 {
+		c.ServerStatsTimeout = 5 * time.Second
+	}
+
+	if c.SlowlogEntries == 0 {
+		c.SlowlogEntries = 128
+	}
+
+	if c.ErrorClassifier == nil {
+		c.ErrorClassifier = ClassifyError
+	}
+
+	if c.KeyspaceExtractor == nil {
+		c.KeyspaceExtractor = DefaultKeyspaceExtractor
+	}
 }
 
 type recorder struct {
 	Registry                        prometheus.Registerer
+	gatherer                        prometheus.Gatherer
 	RedisRequestsTotal              *prometheus.CounterVec
 	RedisRequestsDurationsHistogram *prometheus.HistogramVec
+	RedisPipelineDurationsHistogram *prometheus.HistogramVec
+	RedisPipelineSizeHistogram      *prometheus.HistogramVec
+	RedisCacheLookupsTotal          *prometheus.CounterVec
+	RedisRequestSizeHistogram       *prometheus.HistogramVec
+	RedisReplySizeHistogram         *prometheus.HistogramVec
+	RedisDialsTotal                 *prometheus.CounterVec
+	RedisDialDurationsHistogram     *prometheus.HistogramVec
+	RedisTopologyEventsTotal        *prometheus.CounterVec
+	RedisScriptExecutionsTotal      *prometheus.CounterVec
+	RedisScriptDurationsHistogram   *prometheus.HistogramVec
+	RedisScriptFallbacksTotal       *prometheus.CounterVec
+	RedisPubSubPublishedTotal       *prometheus.CounterVec
+	RedisPubSubReceivedTotal        *prometheus.CounterVec
+	RedisPubSubSubscriptions        *prometheus.GaugeVec
+	RedisPubSubLagHistogram         *prometheus.HistogramVec
+	RedisPubSubReconnectsTotal      *prometheus.CounterVec
+	nodeLabel                       bool
+	classifyError                   func(err error) string
+	keyspaces                       *keyspaces
+	scripts                         *scripts
+	channels                        *channels
 }
 
+// NewRedisRecorder creates Redis recorder and registers its metrics, it panics if registration fails.
 func NewRedisRecorder(appName string, config Config) metrics.RedisRecorder {
+	r, err := RegisterRedisRecorder(appName, config)
+	if err != nil {
+		panic(err)
+	}
+	return r
+}
+
+// RegisterRedisRecorder creates Redis recorder and registers its metrics. Metrics which have been already registered
+// by another recorder with the same configuration are reused.
+func RegisterRedisRecorder(appName string, config Config) (metrics.RedisRecorder, error) {
 	config.defaults()
 
+	if err := config.Validate(labelCommand, labelKeyspace, labelStatus, labelPipeline, labelResult, labelNode, labelEvent, labelScript, labelChannel); err != nil {
+		return nil, err
+	}
+
+	constLabels := config.Labels(appName)
+
+	// Commands sent within pipelines are counted with other commands, the pipeline label tells them apart.
+	var (
+		requestLabels  = []string{labelCommand, labelKeyspace, labelStatus, labelPipeline}
+		durationLabels = []string{labelCommand, labelKeyspace, labelStatus}
+		pipelineLabels = []string{labelPipeline, labelStatus}
+	)
+	if config.NodeLabel {
+		requestLabels = append(requestLabels, labelNode)
+		durationLabels = append(durationLabels, labelNode)
+		pipelineLabels = append(pipelineLabels, labelNode)
+	}
+
 	r := &recorder{
 		RedisRequestsTotal: prometheus.NewCounterVec(prometheus.CounterOpts{
-			Namespace:   "app",
-			Subsystem:   "redis",
+			Namespace:   config.Namespace,
+			Subsystem:   config.Subsystem,
 			Name:        "requests_total",
 			Help:        "The total number of processed requests.",
-			ConstLabels: map[string]string{labelApp: appName},
-		}, []string{labelCommand, labelKeyspace, labelStatus}),
+			ConstLabels: constLabels,
+		}, requestLabels),
 
 		RedisRequestsDurationsHistogram: prometheus.NewHistogramVec(prometheus.HistogramOpts{
-			Namespace:   "app",
-			Subsystem:   "redis",
+			Namespace:   config.Namespace,
+			Subsystem:   config.Subsystem,
 			Name:        "request_duration_seconds",
 			Help:        "The latency of the Redis requests.",
 			Buckets:     config.DurationBuckets,
-			ConstLabels: map[string]string{labelApp: appName},
-		}, []string{labelCommand, labelKeyspace, labelStatus}),
+			ConstLabels: constLabels,
+		}, durationLabels),
+
+		RedisPipelineDurationsHistogram: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace:   config.Namespace,
+			Subsystem:   config.Subsystem,
+			Name:        "pipeline_duration_seconds",
+			Help:        "The latency of the Redis pipelines.",
+			Buckets:     config.DurationBuckets,
+			ConstLabels: constLabels,
+		}, pipelineLabels),
+
+		RedisPipelineSizeHistogram: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace:   config.Namespace,
+			Subsystem:   config.Subsystem,
+			Name:        "pipeline_size",
+			Help:        "The number of requests sent within Redis pipelines.",
+			Buckets:     config.PipelineSizeBuckets,
+			ConstLabels: constLabels,
+		}, []string{labelPipeline}),
+
+		RedisCacheLookupsTotal: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace:   config.Namespace,
+			Subsystem:   config.Subsystem,
+			Name:        "cache_lookups_total",
+			Help:        "The total number of keys and fields looked up by read commands.",
+			ConstLabels: constLabels,
+		}, []string{labelKeyspace, labelResult}),
+
+		RedisRequestSizeHistogram: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace:   config.Namespace,
+			Subsystem:   config.Subsystem,
+			Name:        "request_size_bytes",
+			Help:        "The estimated size of arguments of the Redis requests.",
+			Buckets:     config.SizeBuckets,
+			ConstLabels: constLabels,
+		}, []string{labelCommand, labelKeyspace}),
+
+		RedisReplySizeHistogram: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace:   config.Namespace,
+			Subsystem:   config.Subsystem,
+			Name:        "reply_size_bytes",
+			Help:        "The estimated size of the Redis replies.",
+			Buckets:     config.SizeBuckets,
+			ConstLabels: constLabels,
+		}, []string{labelCommand, labelKeyspace}),
+
+		RedisDialsTotal: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace:   config.Namespace,
+			Subsystem:   config.Subsystem,
+			Name:        "dials_total",
+			Help:        "The total number of dialed connections to Redis nodes.",
+			ConstLabels: constLabels,
+		}, []string{labelNode, labelStatus}),
+
+		RedisDialDurationsHistogram: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace:   config.Namespace,
+			Subsystem:   config.Subsystem,
+			Name:        "dial_duration_seconds",
+			Help:        "The latency of dialing connections to Redis nodes.",
+			Buckets:     config.DurationBuckets,
+			ConstLabels: constLabels,
+		}, []string{labelNode, labelStatus}),
+
+		RedisTopologyEventsTotal: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace:   config.Namespace,
+			Subsystem:   config.Subsystem,
+			Name:        "topology_events_total",
+			Help:        "The total number of redirects, cluster slots reloads and failovers of Redis nodes.",
+			ConstLabels: constLabels,
+		}, []string{labelNode, labelEvent}),
+
+		RedisScriptExecutionsTotal: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace:   config.Namespace,
+			Subsystem:   config.Subsystem,
+			Name:        "script_executions_total",
+			Help:        "The total number of executions of Lua scripts by EVAL and EVALSHA commands.",
+			ConstLabels: constLabels,
+		}, []string{labelScript, labelStatus}),
+
+		RedisScriptDurationsHistogram: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace:   config.Namespace,
+			Subsystem:   config.Subsystem,
+			Name:        "script_duration_seconds",
+			Help:        "The latency of executions of Lua scripts sent outside of pipelines.",
+			Buckets:     config.DurationBuckets,
+			ConstLabels: constLabels,
+		}, []string{labelScript, labelStatus}),
+
+		RedisScriptFallbacksTotal: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace:   config.Namespace,
+			Subsystem:   config.Subsystem,
+			Name:        "script_fallbacks_total",
+			Help:        "The total number of EVALSHA executions of Lua scripts missing in the script cache (NOSCRIPT).",
+			ConstLabels: constLabels,
+		}, []string{labelScript}),
+
+		RedisPubSubPublishedTotal: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace:   config.Namespace,
+			Subsystem:   config.Subsystem,
+			Name:        "pubsub_published_messages_total",
+			Help:        "The total number of messages published to Pub/Sub channels.",
+			ConstLabels: constLabels,
+		}, []string{labelChannel, labelStatus}),
+
+		RedisPubSubReceivedTotal: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace:   config.Namespace,
+			Subsystem:   config.Subsystem,
+			Name:        "pubsub_received_messages_total",
+			Help:        "The total number of messages received from Pub/Sub subscriptions.",
+			ConstLabels: constLabels,
+		}, []string{labelChannel}),
+
+		RedisPubSubSubscriptions: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace:   config.Namespace,
+			Subsystem:   config.Subsystem,
+			Name:        "pubsub_subscriptions",
+			Help:        "The number of active subscriptions to Pub/Sub channels and patterns.",
+			ConstLabels: constLabels,
+		}, []string{labelChannel}),
+
+		RedisPubSubLagHistogram: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace:   config.Namespace,
+			Subsystem:   config.Subsystem,
+			Name:        "pubsub_receive_lag_seconds",
+			Help:        "The time received Pub/Sub messages waited for the receiver.",
+			Buckets:     config.DurationBuckets,
+			ConstLabels: constLabels,
+		}, []string{labelChannel}),
+
+		RedisPubSubReconnectsTotal: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace:   config.Namespace,
+			Subsystem:   config.Subsystem,
+			Name:        "pubsub_reconnects_total",
+			Help:        "The total number of reconnects of Pub/Sub subscriptions.",
+			ConstLabels: constLabels,
+		}, nil),
 	}
 
-	r.Registry = prometheus.DefaultRegisterer
+	r.Registry = config.Registerer
+	r.gatherer = config.Gatherer
+	r.nodeLabel = config.NodeLabel
+	r.classifyError = config.ErrorClassifier
+	r.keyspaces = newKeyspaces(config.KeyspaceExtractor, config.Keyspaces)
+	r.scripts = newScripts(config.Scripts)
+	r.channels = newChannels(config.Channels)
 
-	r.Registry.MustRegister(
-		r.RedisRequestsTotal,
-		r.RedisRequestsDurationsHistogram,
+	err := metrics.Register(r.Registry,
+		&r.RedisRequestsTotal,
+		metrics.Histogram(&r.RedisRequestsDurationsHistogram, config.DurationBuckets),
+		metrics.Histogram(&r.RedisPipelineDurationsHistogram, config.DurationBuckets),
+		metrics.Histogram(&r.RedisPipelineSizeHistogram, config.PipelineSizeBuckets),
+		&r.RedisCacheLookupsTotal,
+		metrics.Histogram(&r.RedisRequestSizeHistogram, config.SizeBuckets),
+		metrics.Histogram(&r.RedisReplySizeHistogram, config.SizeBuckets),
+		&r.RedisDialsTotal,
+		metrics.Histogram(&r.RedisDialDurationsHistogram, config.DurationBuckets),
+		&r.RedisTopologyEventsTotal,
+		&r.RedisScriptExecutionsTotal,
+		metrics.Histogram(&r.RedisScriptDurationsHistogram, config.DurationBuckets),
+		&r.RedisScriptFallbacksTotal,
+		&r.RedisPubSubPublishedTotal,
+		&r.RedisPubSubReceivedTotal,
+		&r.RedisPubSubSubscriptions,
+		metrics.Histogram(&r.RedisPubSubLagHistogram, config.DurationBuckets),
+		&r.RedisPubSubReconnectsTotal,
 	)
+	if err != nil {
+		return nil, err
+	}
 
-	return r
+	return r, nil
 }
 
 // Collect updates metrics using passed properties
@@ -79,26 +361,145 @@ func (r recorder) Collect(props metrics.RedisReqProperties, duration time.Durati
 		space   = props.Keyspace
 	)
 
-	r.RedisRequestsTotal.WithLabelValues(command, space, code).Inc()
-	r.RedisRequestsDurationsHistogram.WithLabelValues(command, space, code).Observe(duration.Seconds())
+	r.RedisRequestsTotal.WithLabelValues(r.withNode(props.Node, command, space, code, pipelineTypeNone)...).Inc()
+	r.RedisRequestsDurationsHistogram.WithLabelValues(r.withNode(props.Node, command, space, code)...).Observe(duration.Seconds())
+	r.collectLookups(props)
+	r.collectSizes(props)
+	r.collectScript(props)
+	if props.Script != "" {
+		r.RedisScriptDurationsHistogram.WithLabelValues(props.Script, code).Observe(duration.Seconds())
+	}
+}
+
+// CollectPipeline updates pipeline metrics using passed properties of the pipeline and its commands
+func (r recorder) CollectPipeline(props metrics.RedisPipelineProperties, cmds []metrics.RedisReqProperties, duration time.Duration) {
+	for _, cmd := range cmds {
+		r.RedisRequestsTotal.WithLabelValues(r.withNode(cmd.Node, cmd.Command, cmd.Keyspace, cmd.Code, props.Type)...).Inc()
+		r.collectLookups(cmd)
+		r.collectSizes(cmd)
+		r.collectScript(cmd)
+	}
+
+	r.RedisPipelineDurationsHistogram.WithLabelValues(r.withNode(props.Node, props.Type, props.Code)...).Observe(duration.Seconds())
+	r.RedisPipelineSizeHistogram.WithLabelValues(props.Type).Observe(float64(len(cmds)))
+}
+
+// CollectDial updates dial metrics using passed properties
+func (r recorder) CollectDial(props metrics.RedisDialProperties, duration time.Duration) {
+	r.RedisDialsTotal.WithLabelValues(props.Node, props.Code).Inc()
+	r.RedisDialDurationsHistogram.WithLabelValues(props.Node, props.Code).Observe(duration.Seconds())
+}
+
+// CollectTopology updates metrics of topology events using passed properties
+func (r recorder) CollectTopology(props metrics.RedisTopologyProperties) {
+	r.RedisTopologyEventsTotal.WithLabelValues(props.Node, props.Event).Inc()
+}
+
+// CollectPublish updates metrics of published messages using passed properties
+func (r recorder) CollectPublish(props metrics.RedisPubSubProperties) {
+	r.RedisPubSubPublishedTotal.WithLabelValues(props.Channel, props.Code).Inc()
+}
+
+// CollectReceive updates metrics of received messages using passed properties, lag is not observed if it is negative
+func (r recorder) CollectReceive(props metrics.RedisPubSubProperties, lag time.Duration) {
+	r.RedisPubSubReceivedTotal.WithLabelValues(props.Channel).Inc()
+	if lag >= 0 {
+		r.RedisPubSubLagHistogram.WithLabelValues(props.Channel).Observe(lag.Seconds())
+	}
+}
+
+// CollectSubscriptions changes the number of active subscriptions by delta
+func (r recorder) CollectSubscriptions(props metrics.RedisPubSubProperties, delta int) {
+	r.RedisPubSubSubscriptions.WithLabelValues(props.Channel).Add(float64(delta))
+}
+
+// CollectPubSubReconnect increments the number of reconnects of subscriptions
+func (r recorder) CollectPubSubReconnect() {
+	r.RedisPubSubReconnectsTotal.WithLabelValues().Inc()
+}
+
+// withNode appends node to label values if requests are labelled by nodes.
+func (r recorder) withNode(node string, values ...string) []string {
+	if r.nodeLabel {
+		values = append(values, node)
+	}
+	return values
+}
+
+// collectLookups updates cache metrics using hits and misses of the command.
+func (r recorder) collectLookups(props metrics.RedisReqProperties) {
+	if props.Hits > 0 {
+		r.RedisCacheLookupsTotal.WithLabelValues(props.Keyspace, resultHit).Add(float64(props.Hits))
+	}
+	if props.Misses > 0 {
+		r.RedisCacheLookupsTotal.WithLabelValues(props.Keyspace, resultMiss).Add(float64(props.Misses))
+	}
+}
+
+// collectSizes updates size metrics of the command, size of the reply is not observed if it has not been received.
+func (r recorder) collectSizes(props metrics.RedisReqProperties) {
+	r.RedisRequestSizeHistogram.WithLabelValues(props.Command, props.Keyspace).Observe(float64(props.RequestSize))
+	if props.ReplySize >= 0 {
+		r.RedisReplySizeHistogram.WithLabelValues(props.Command, props.Keyspace).Observe(float64(props.ReplySize))
+	}
+}
+
+// collectScript updates metrics of executions of Lua scripts by the command.
+func (r recorder) collectScript(props metrics.RedisReqProperties) {
+	if props.Script == "" {
+		return
+	}
+
+	r.RedisScriptExecutionsTotal.WithLabelValues(props.Script, props.Code).Inc()
+	if props.NoScript {
+		r.RedisScriptFallbacksTotal.WithLabelValues(props.Script).Inc()
+	}
+}
+
+// Gatherer returns the gatherer used for gathering recorded metrics.
+func (r recorder) Gatherer() prometheus.Gatherer {
+	return r.gatherer
 }
 
 // Unregister ...
 func (r recorder) Unregister() {
-	r.Registry.Unregister(r.RedisRequestsTotal)
-	r.Registry.Unregister(r.RedisRequestsDurationsHistogram)
+	metrics.Unregister(r.Registry,
+		r.RedisRequestsTotal,
+		r.RedisRequestsDurationsHistogram,
+		r.RedisPipelineDurationsHistogram,
+		r.RedisPipelineSizeHistogram,
+		r.RedisCacheLookupsTotal,
+		r.RedisRequestSizeHistogram,
+		r.RedisReplySizeHistogram,
+		r.RedisDialsTotal,
+		r.RedisDialDurationsHistogram,
+		r.RedisTopologyEventsTotal,
+		r.RedisScriptExecutionsTotal,
+		r.RedisScriptDurationsHistogram,
+		r.RedisScriptFallbacksTotal,
+		r.RedisPubSubPublishedTotal,
+		r.RedisPubSubReceivedTotal,
+		r.RedisPubSubSubscriptions,
+		r.RedisPubSubLagHistogram,
+		r.RedisPubSubReconnectsTotal,
+	)
 }
 
 func (r recorder) NewCollectHook() redis.Hook {
+	return NewCollectHook(&r)
+}
+
+// NewCollectHook returns redis.Hook which passes properties of processed commands to recorder, see NewObserver.
+func NewCollectHook(recorder metrics.RedisRecorder) redis.Hook {
 	hook := &CollectHook{
-		recorder: r,
+		observer: NewObserver(recorder),
 	}
 	return redis.Hook(hook)
 }
 
 // CollectHook is an implementation of redis.Hook interface
 type CollectHook struct {
-	recorder
+	observer *Observer
 }
 
 func (h *CollectHook) BeforeProcess(ctx context.Context, _ redis.Cmder) (context.Context, error) {
@@ -107,38 +508,29 @@ func (h *CollectHook) BeforeProcess(ctx context.Context, _ redis.Cmder) (context
 }
 
 func (h *CollectHook) BeforeProcessPipeline(ctx context.Context, _ []redis.Cmder) (context.Context, error) {
-	// no-op method
+	ctx = context.WithValue(ctx, keyRequestStart, time.Now())
 	return ctx, nil
 }
 
-func (h *CollectHook) AfterProcessPipeline(_ context.Context, _ []redis.Cmder) error {
-	// no-op method
-	return nil
-}
+func (h *CollectHook) AfterProcessPipeline(ctx context.Context, cmds []redis.Cmder) error {
+	// Extract request start time from context
+	start := ctx.Value(keyRequestStart).(time.Time)
 
-func (h *CollectHook) AfterProcess(ctx context.Context, cmd redis.Cmder) error {
-	var props = metrics.RedisReqProperties{
-		Command: cmd.Name(),
-	}
-	if cmd.Err() != nil {
-		props.Code = "err"
-	} else {
-		props.Code = "ok"
+	var observed = make([]Cmder, 0, len(cmds))
+	for _, cmd := range cmds {
+		observed = append(observed, cmd)
 	}
 
-	// If number of passed arguments greater than than 1 it means we can extract key of GET/SET/DEL/etc commands.
-	// Regexp adjusted to the following format of keys - 'app-name/keyspace/...', hence using the regexp below, the app-name
-	// will be omitted (app-name is already passed as a part of metric) and rest of key will be extracted.
-	if len(cmd.Args()) >= 2 {
-		argsStr := strings.Split(cmd.String(), " ")
-		re := regexp.MustCompile(`(/[a-z-]{1,})+`)
-		props.Keyspace = re.FindString(argsStr[1])
-	}
+	h.observer.ObservePipeline(observed, time.Since(start))
+
+	return nil
+}
 
+func (h *CollectHook) AfterProcess(ctx context.Context, cmd redis.Cmder) error {
 	// Extract request start time from context
 	start := ctx.Value(keyRequestStart).(time.Time)
 
-	h.recorder.Collect(props, time.Since(start))
+	h.observer.ObserveCommand(cmd, time.Since(start))
 
 	return nil
 }
@@ -1,10 +1,15 @@
 package redis_test
 
 import (
+	"context"
+	"errors"
+	"github.com/go-redis/redis/v7"
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 	"github.com/weaponry/go-instrumenting/metrics"
+	"github.com/weaponry/go-instrumenting/metrics/metricstest"
 	redismetrics "github.com/weaponry/go-instrumenting/metrics/redis"
 	"io/ioutil"
 	"net/http"
@@ -61,8 +66,8 @@ func TestNewRedisRecorder(t *testing.T) {
 				`app_redis_request_duration_seconds_sum{application="test-app",command="GET",keyspace="example",status="err"} 0.04`,
 				`app_redis_request_duration_seconds_count{application="test-app",command="GET",keyspace="example",status="err"} 2`,
 
-				`app_redis_requests_total{application="test-app",command="SET",keyspace="example",status="ok"} 3`,
-				`app_redis_requests_total{application="test-app",command="GET",keyspace="example",status="err"} 2`,
+				`app_redis_requests_total{application="test-app",command="SET",keyspace="example",pipeline="none",status="ok"} 3`,
+				`app_redis_requests_total{application="test-app",command="GET",keyspace="example",pipeline="none",status="err"} 2`,
 			},
 		},
 		{
@@ -109,8 +114,24 @@ func TestNewRedisRecorder(t *testing.T) {
 				`app_redis_request_duration_seconds_sum{application="test-app",command="GET",keyspace="example",status="err"} 540`,
 				`app_redis_request_duration_seconds_count{application="test-app",command="GET",keyspace="example",status="err"} 3`,
 
-				`app_redis_requests_total{application="test-app",command="GET",keyspace="example",status="err"} 3`,
-				`app_redis_requests_total{application="test-app",command="SET",keyspace="example",status="ok"} 3`,
+				`app_redis_requests_total{application="test-app",command="GET",keyspace="example",pipeline="none",status="err"} 3`,
+				`app_redis_requests_total{application="test-app",command="SET",keyspace="example",pipeline="none",status="ok"} 3`,
+			},
+		},
+		{
+			name: "Custom options should be used for naming metrics.",
+			config: redismetrics.Config{
+				Options: metrics.Options{
+					Namespace:   "acme",
+					ConstLabels: prometheus.Labels{"region": "eu"},
+				},
+			},
+			recordMetrics: func(r metrics.RedisRecorder) {
+				r.Collect(metrics.RedisReqProperties{Keyspace: "example", Command: "SET", Code: "ok"}, 10*time.Millisecond)
+			},
+			expMetrics: []string{
+				`acme_redis_request_duration_seconds_count{application="test-app",command="SET",keyspace="example",region="eu",status="ok"} 1`,
+				`acme_redis_requests_total{application="test-app",command="SET",keyspace="example",pipeline="none",region="eu",status="ok"} 1`,
 			},
 		},
 	}
@@ -139,3 +160,125 @@ func TestNewRedisRecorder(t *testing.T) {
 		})
 	}
 }
+
+func TestCollectHookPipeline(t *testing.T) {
+	newCmd := func(err error, args ...interface{}) redis.Cmder {
+		cmd := redis.NewStringCmd(args...)
+		cmd.SetErr(err)
+		return cmd
+	}
+
+	testCases := []struct {
+		name       string
+		cmds       []redis.Cmder
+		expMetrics []string
+	}{
+		{
+			name: "Pipeline commands should be measured per command.",
+			cmds: []redis.Cmder{
+				newCmd(nil, "set", "app/users/1", "value"),
+				newCmd(nil, "set", "app/users/2", "value"),
+				newCmd(nil, "get", "app/orders/1"),
+			},
+			expMetrics: []string{
+				`app_redis_requests_total{application="test-app",command="set",keyspace="/users",pipeline="pipeline",status="ok"} 2`,
+				`app_redis_requests_total{application="test-app",command="get",keyspace="/orders",pipeline="pipeline",status="ok"} 1`,
+				`app_redis_pipeline_duration_seconds_count{application="test-app",pipeline="pipeline",status="ok"} 1`,
+				`app_redis_pipeline_size_bucket{application="test-app",pipeline="pipeline",le="2"} 0`,
+				`app_redis_pipeline_size_bucket{application="test-app",pipeline="pipeline",le="4"} 1`,
+				`app_redis_pipeline_size_sum{application="test-app",pipeline="pipeline"} 3`,
+			},
+		},
+		{
+			name: "Missing keys should not fail the pipeline.",
+			cmds: []redis.Cmder{
+				newCmd(redis.Nil, "get", "app/sessions/1"),
+				newCmd(nil, "get", "app/sessions/2"),
+			},
+			expMetrics: []string{
+				`app_redis_requests_total{application="test-app",command="get",keyspace="/sessions",pipeline="pipeline",status="nil"} 1`,
+				`app_redis_requests_total{application="test-app",command="get",keyspace="/sessions",pipeline="pipeline",status="ok"} 1`,
+				`app_redis_pipeline_duration_seconds_count{application="test-app",pipeline="pipeline",status="ok"} 1`,
+			},
+		},
+		{
+			name: "Transaction should be measured without MULTI/EXEC commands.",
+			cmds: []redis.Cmder{
+				redis.NewStatusCmd("multi"),
+				newCmd(nil, "incr", "app/counters/1"),
+				newCmd(errors.New("ERR value is not an integer"), "incr", "app/counters/2"),
+				redis.NewSliceCmd("exec"),
+			},
+			expMetrics: []string{
+				`app_redis_requests_total{application="test-app",command="incr",keyspace="/counters",pipeline="tx",status="ok"} 1`,
+				`app_redis_requests_total{application="test-app",command="incr",keyspace="/counters",pipeline="tx",status="other"} 1`,
+				`app_redis_pipeline_duration_seconds_count{application="test-app",pipeline="tx",status="other"} 1`,
+				`app_redis_pipeline_size_sum{application="test-app",pipeline="tx"} 2`,
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			metricRecorder := redismetrics.NewRedisRecorder("test-app", redismetrics.Config{})
+			hook := metricRecorder.NewCollectHook()
+
+			ctx, err := hook.BeforeProcessPipeline(context.Background(), tc.cmds)
+			assert.NoError(t, err)
+			assert.NoError(t, hook.AfterProcessPipeline(ctx, tc.cmds))
+
+			// Get the metrics handler and serve.
+			rec := httptest.NewRecorder()
+			req := httptest.NewRequest("GET", "/metrics", nil)
+			promhttp.HandlerFor(prometheus.DefaultGatherer, promhttp.HandlerOpts{}).ServeHTTP(rec, req)
+
+			resp := rec.Result()
+
+			// Check all metrics are present.
+			if assert.Equal(t, http.StatusOK, resp.StatusCode) {
+				body, _ := ioutil.ReadAll(resp.Body)
+				for _, expMetric := range tc.expMetrics {
+					assert.Contains(t, string(body), expMetric, "metric not present on the result")
+				}
+			}
+			metricRecorder.Unregister()
+		})
+	}
+}
+
+func TestRegisterRedisRecorder(t *testing.T) {
+	registry := prometheus.NewRegistry()
+
+	// Recorders with the same configuration should share metrics, e.g. when used with two Redis clients.
+	first, err := redismetrics.RegisterRedisRecorder("test-app", redismetrics.Config{Options: metrics.Options{Registerer: registry}})
+	require.NoError(t, err)
+	second, err := redismetrics.RegisterRedisRecorder("test-app", redismetrics.Config{Options: metrics.Options{Registerer: registry}})
+	require.NoError(t, err)
+
+	first.Collect(metrics.RedisReqProperties{Keyspace: "example", Command: "GET", Code: "ok"}, time.Millisecond)
+	second.Collect(metrics.RedisReqProperties{Keyspace: "example", Command: "GET", Code: "ok"}, time.Millisecond)
+
+	// Get the metrics handler and serve.
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest("GET", "/metrics", nil)
+	promhttp.HandlerFor(registry, promhttp.HandlerOpts{}).ServeHTTP(rec, req)
+
+	body, _ := ioutil.ReadAll(rec.Result().Body)
+	assert.Contains(t, string(body), `app_redis_requests_total{application="test-app",command="GET",keyspace="example",pipeline="none",status="ok"} 2`)
+
+	// Shared metrics should be kept until both recorders are unregistered.
+	first.Unregister()
+	second.Collect(metrics.RedisReqProperties{Keyspace: "example", Command: "GET", Code: "ok"}, time.Millisecond)
+	metricstest.AssertCounter(t, registry, "app_redis_requests_total", prometheus.Labels{"command": "GET"}, 3)
+	second.Unregister()
+	metricstest.AssertCounter(t, registry, "app_redis_requests_total", prometheus.Labels{"command": "GET"}, 0)
+
+	// Metrics with the same name but different labels should not be registered.
+	conflicting := prometheus.NewRegistry()
+	conflicting.MustRegister(prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: "app", Subsystem: "redis", Name: "requests_total", Help: "Conflicting metric.",
+	}))
+
+	_, err = redismetrics.RegisterRedisRecorder("test-app", redismetrics.Config{Options: metrics.Options{Registerer: conflicting}})
+	assert.Error(t, err)
+}
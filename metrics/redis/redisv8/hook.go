@@ -0,0 +1,62 @@
+// Package redisv8 provides hooks collecting metrics of commands processed by clients of
+// github.com/go-redis/redis/v8, metrics are recorded by recorders of metrics/redis package.
+package redisv8
+
+import (
+	"context"
+	"github.com/go-redis/redis/v8"
+	"github.com/weaponry/go-instrumenting/metrics"
+	redismetrics "github.com/weaponry/go-instrumenting/metrics/redis"
+	"time"
+)
+
+const keyRequestStart key = iota
+
+type key int
+
+// NewCollectHook returns redis.Hook which passes properties of processed commands and pipelines to recorder, see
+// redismetrics.NewObserver.
+func NewCollectHook(recorder metrics.RedisRecorder) redis.Hook {
+	hook := &CollectHook{
+		observer: redismetrics.NewObserver(recorder),
+	}
+	return redis.Hook(hook)
+}
+
+// CollectHook is an implementation of redis.Hook interface
+type CollectHook struct {
+	observer *redismetrics.Observer
+}
+
+func (h *CollectHook) BeforeProcess(ctx context.Context, _ redis.Cmder) (context.Context, error) {
+	ctx = context.WithValue(ctx, keyRequestStart, time.Now())
+	return ctx, nil
+}
+
+func (h *CollectHook) BeforeProcessPipeline(ctx context.Context, _ []redis.Cmder) (context.Context, error) {
+	ctx = context.WithValue(ctx, keyRequestStart, time.Now())
+	return ctx, nil
+}
+
+func (h *CollectHook) AfterProcessPipeline(ctx context.Context, cmds []redis.Cmder) error {
+	// Extract request start time from context
+	start := ctx.Value(keyRequestStart).(time.Time)
+
+	var observed = make([]redismetrics.Cmder, 0, len(cmds))
+	for _, cmd := range cmds {
+		observed = append(observed, cmd)
+	}
+
+	h.observer.ObservePipeline(observed, time.Since(start))
+
+	return nil
+}
+
+func (h *CollectHook) AfterProcess(ctx context.Context, cmd redis.Cmder) error {
+	// Extract request start time from context
+	start := ctx.Value(keyRequestStart).(time.Time)
+
+	h.observer.ObserveCommand(cmd, time.Since(start))
+
+	return nil
+}
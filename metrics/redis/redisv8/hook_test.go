@@ -0,0 +1,67 @@
+package redisv8_test
+
+import (
+	"context"
+	"github.com/go-redis/redis/v8"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/weaponry/go-instrumenting/metrics"
+	"github.com/weaponry/go-instrumenting/metrics/metricstest"
+	redismetrics "github.com/weaponry/go-instrumenting/metrics/redis"
+	redisv8metrics "github.com/weaponry/go-instrumenting/metrics/redis/redisv8"
+	"testing"
+)
+
+func TestCollectHook(t *testing.T) {
+	registry := prometheus.NewRegistry()
+	recorder := redismetrics.NewRedisRecorder("test-app", redismetrics.Config{
+		Options:           metrics.Options{Registerer: registry},
+		KeyspaceExtractor: redismetrics.NewDelimiterExtractor(":", 2),
+	})
+	hook := redisv8metrics.NewCollectHook(recorder)
+
+	ctx := context.Background()
+
+	found := redis.NewStringCmd(ctx, "get", "app:users:1")
+	found.SetVal("alice")
+	missing := redis.NewStringCmd(ctx, "get", "app:users:2")
+	missing.SetErr(redis.Nil)
+
+	for _, cmd := range []redis.Cmder{found, missing} {
+		ctx, err := hook.BeforeProcess(ctx, cmd)
+		require.NoError(t, err)
+		require.NoError(t, hook.AfterProcess(ctx, cmd))
+	}
+
+	// Keyspaces should be extracted as configured for the recorder.
+	metricstest.AssertCounter(t, registry, "app_redis_requests_total", prometheus.Labels{"keyspace": "app:users", "status": "ok"}, 1)
+	metricstest.AssertCounter(t, registry, "app_redis_requests_total", prometheus.Labels{"keyspace": "app:users", "status": "nil"}, 1)
+	metricstest.AssertCounter(t, registry, "app_redis_cache_lookups_total", prometheus.Labels{"result": "hit"}, 1)
+	metricstest.AssertCounter(t, registry, "app_redis_cache_lookups_total", prometheus.Labels{"result": "miss"}, 1)
+	assert.Equal(t, float64(5), metricstest.HistogramSum(t, registry, "app_redis_reply_size_bytes", nil))
+}
+
+func TestCollectHookPipeline(t *testing.T) {
+	recorder := metricstest.NewRedisRecorder()
+	hook := redisv8metrics.NewCollectHook(recorder)
+
+	ctx := context.Background()
+	cmds := []redis.Cmder{
+		redis.NewStatusCmd(ctx, "multi"),
+		redis.NewIntCmd(ctx, "incr", "app/counters/1"),
+		redis.NewSliceCmd(ctx, "exec"),
+	}
+	cmds[2].SetErr(redis.TxFailedErr)
+
+	ctx, err := hook.BeforeProcessPipeline(ctx, cmds)
+	require.NoError(t, err)
+	require.NoError(t, hook.AfterProcessPipeline(ctx, cmds))
+
+	calls := recorder.PipelineCalls()
+	require.Len(t, calls, 1)
+	assert.Equal(t, "tx", calls[0].Props.Type)
+	assert.Equal(t, "other", calls[0].Props.Code)
+	require.Len(t, calls[0].Cmds, 1)
+	assert.Equal(t, "/counters", calls[0].Cmds[0].Keyspace)
+}
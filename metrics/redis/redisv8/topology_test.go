@@ -0,0 +1,46 @@
+package redisv8_test
+
+import (
+	"context"
+	"github.com/go-redis/redis/v8"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/weaponry/go-instrumenting/metrics"
+	"github.com/weaponry/go-instrumenting/metrics/metricstest"
+	redismetrics "github.com/weaponry/go-instrumenting/metrics/redis"
+	redisv8metrics "github.com/weaponry/go-instrumenting/metrics/redis/redisv8"
+	"testing"
+)
+
+func TestNewClusterNodeClientFunc(t *testing.T) {
+	registry := prometheus.NewRegistry()
+	recorder := redismetrics.NewRedisRecorder("test-app", redismetrics.Config{
+		Options:   metrics.Options{Registerer: registry},
+		NodeLabel: true,
+	})
+
+	newClient := redisv8metrics.NewClusterNodeClientFunc(recorder, nil)
+	client := newClient(&redis.Options{Addr: "10.0.0.1:6379"})
+	defer func() { _ = client.Close() }()
+
+	client.AddHook(replyHook{err: redis.Nil})
+	_ = client.Get(context.Background(), "app/users/1").Err()
+
+	metricstest.AssertCounter(t, registry, "app_redis_requests_total", prometheus.Labels{"node": "10.0.0.1:6379", "status": "nil"}, 1)
+}
+
+// replyHook replies to commands with passed error without sending them to the server.
+type replyHook struct {
+	err error
+}
+
+func (h replyHook) BeforeProcess(ctx context.Context, _ redis.Cmder) (context.Context, error) {
+	return ctx, h.err
+}
+
+func (h replyHook) AfterProcess(context.Context, redis.Cmder) error { return nil }
+
+func (h replyHook) BeforeProcessPipeline(ctx context.Context, _ []redis.Cmder) (context.Context, error) {
+	return ctx, nil
+}
+
+func (h replyHook) AfterProcessPipeline(context.Context, []redis.Cmder) error { return nil }
@@ -0,0 +1,64 @@
+// Package redisv9 provides hooks collecting metrics of commands processed by clients of
+// github.com/redis/go-redis/v9, metrics are recorded by recorders of metrics/redis package.
+package redisv9
+
+import (
+	"context"
+	"github.com/redis/go-redis/v9"
+	"github.com/weaponry/go-instrumenting/metrics"
+	redismetrics "github.com/weaponry/go-instrumenting/metrics/redis"
+	"net"
+	"time"
+)
+
+// NewCollectHook returns redis.Hook which passes properties of dials, processed commands and pipelines to recorder,
+// see redismetrics.NewObserver.
+func NewCollectHook(recorder metrics.RedisRecorder) redis.Hook {
+	hook := &CollectHook{
+		observer: redismetrics.NewObserver(recorder),
+	}
+	return redis.Hook(hook)
+}
+
+// CollectHook is an implementation of redis.Hook interface
+type CollectHook struct {
+	observer *redismetrics.Observer
+}
+
+func (h *CollectHook) DialHook(next redis.DialHook) redis.DialHook {
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		start := time.Now()
+		conn, err := next(ctx, network, addr)
+
+		h.observer.ObserveDial(addr, err, time.Since(start))
+
+		return conn, err
+	}
+}
+
+func (h *CollectHook) ProcessHook(next redis.ProcessHook) redis.ProcessHook {
+	return func(ctx context.Context, cmd redis.Cmder) error {
+		start := time.Now()
+		err := next(ctx, cmd)
+
+		h.observer.ObserveCommand(cmd, time.Since(start))
+
+		return err
+	}
+}
+
+func (h *CollectHook) ProcessPipelineHook(next redis.ProcessPipelineHook) redis.ProcessPipelineHook {
+	return func(ctx context.Context, cmds []redis.Cmder) error {
+		start := time.Now()
+		err := next(ctx, cmds)
+
+		var observed = make([]redismetrics.Cmder, 0, len(cmds))
+		for _, cmd := range cmds {
+			observed = append(observed, cmd)
+		}
+
+		h.observer.ObservePipeline(observed, time.Since(start))
+
+		return err
+	}
+}
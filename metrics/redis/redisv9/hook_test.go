@@ -0,0 +1,131 @@
+package redisv9_test
+
+import (
+	"context"
+	"errors"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/weaponry/go-instrumenting/metrics"
+	"github.com/weaponry/go-instrumenting/metrics/metricstest"
+	redismetrics "github.com/weaponry/go-instrumenting/metrics/redis"
+	redisv9metrics "github.com/weaponry/go-instrumenting/metrics/redis/redisv9"
+	"net"
+	"os"
+	"syscall"
+	"testing"
+)
+
+func TestCollectHookProcess(t *testing.T) {
+	testCases := []struct {
+		name      string
+		cmd       redis.Cmder
+		reply     func(cmd redis.Cmder)
+		expStatus string
+		expResult string
+	}{
+		{
+			name:      "Found values should be measured as hits.",
+			cmd:       redis.NewStringCmd(context.Background(), "get", "app:users:1"),
+			reply:     func(cmd redis.Cmder) { cmd.(*redis.StringCmd).SetVal("alice") },
+			expStatus: "ok",
+			expResult: "hit",
+		},
+		{
+			name:      "Missing values of v9 should be measured as misses.",
+			cmd:       redis.NewStringCmd(context.Background(), "get", "app:users:1"),
+			reply:     func(cmd redis.Cmder) { cmd.SetErr(redis.Nil) },
+			expStatus: "nil",
+			expResult: "miss",
+		},
+		{
+			name:      "Elements of arrays should be measured.",
+			cmd:       redis.NewSliceCmd(context.Background(), "mget", "app:users:1", "app:users:2"),
+			reply:     func(cmd redis.Cmder) { cmd.(*redis.SliceCmd).SetVal([]interface{}{"alice", nil}) },
+			expStatus: "ok",
+			expResult: "hit",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			registry := prometheus.NewRegistry()
+			recorder := redismetrics.NewRedisRecorder("test-app", redismetrics.Config{
+				Options:           metrics.Options{Registerer: registry},
+				KeyspaceExtractor: redismetrics.NewDelimiterExtractor(":", 2),
+			})
+			hook := redisv9metrics.NewCollectHook(recorder)
+
+			process := hook.ProcessHook(func(_ context.Context, cmd redis.Cmder) error {
+				tc.reply(cmd)
+				return cmd.Err()
+			})
+			_ = process(context.Background(), tc.cmd)
+
+			// Keyspaces should be extracted as configured for the recorder.
+			metricstest.AssertCounter(t, registry, "app_redis_requests_total", prometheus.Labels{
+				"command":  tc.cmd.Name(),
+				"keyspace": "app:users",
+				"status":   tc.expStatus,
+			}, 1)
+			assert.NotZero(t, metricstest.CounterValue(t, registry, "app_redis_cache_lookups_total", prometheus.Labels{"result": tc.expResult}))
+		})
+	}
+}
+
+func TestCollectHookProcessPipeline(t *testing.T) {
+	recorder := metricstest.NewRedisRecorder()
+	hook := redisv9metrics.NewCollectHook(recorder)
+
+	ctx := context.Background()
+	cmds := []redis.Cmder{
+		redis.NewStatusCmd(ctx, "multi"),
+		redis.NewIntCmd(ctx, "incr", "app/counters/1"),
+		redis.NewStringCmd(ctx, "get", "app/users/1"),
+		redis.NewSliceCmd(ctx, "exec"),
+	}
+
+	errExec := errors.New("exec failed")
+	process := hook.ProcessPipelineHook(func(_ context.Context, cmds []redis.Cmder) error {
+		cmds[1].(*redis.IntCmd).SetVal(1)
+		cmds[2].SetErr(redis.Nil)
+		return errExec
+	})
+	assert.Equal(t, errExec, process(ctx, cmds))
+
+	calls := recorder.PipelineCalls()
+	require.Len(t, calls, 1)
+	assert.Equal(t, "tx", calls[0].Props.Type)
+	assert.Equal(t, "ok", calls[0].Props.Code)
+	require.Len(t, calls[0].Cmds, 2)
+	assert.Equal(t, "/counters", calls[0].Cmds[0].Keyspace)
+	assert.Equal(t, "nil", calls[0].Cmds[1].Code)
+	assert.Equal(t, 1, calls[0].Cmds[1].Misses)
+}
+
+func TestCollectHookDial(t *testing.T) {
+	registry := prometheus.NewRegistry()
+	recorder := redismetrics.NewRedisRecorder("test-app", redismetrics.Config{Options: metrics.Options{Registerer: registry}})
+	hook := redisv9metrics.NewCollectHook(recorder)
+
+	refused := &net.OpError{Op: "dial", Net: "tcp", Err: os.NewSyscallError("connect", syscall.ECONNREFUSED)}
+	dial := hook.DialHook(func(_ context.Context, _, addr string) (net.Conn, error) {
+		if addr == "10.0.0.2:6379" {
+			return nil, refused
+		}
+		conn, _ := net.Pipe()
+		return conn, nil
+	})
+
+	conn, err := dial(context.Background(), "tcp", "10.0.0.1:6379")
+	require.NoError(t, err)
+	require.NoError(t, conn.Close())
+
+	_, err = dial(context.Background(), "tcp", "10.0.0.2:6379")
+	assert.Equal(t, refused, err)
+
+	metricstest.AssertCounter(t, registry, "app_redis_dials_total", prometheus.Labels{"node": "10.0.0.1:6379", "status": "ok"}, 1)
+	metricstest.AssertCounter(t, registry, "app_redis_dials_total", prometheus.Labels{"node": "10.0.0.2:6379", "status": "conn_refused"}, 1)
+	metricstest.AssertHistogramCount(t, registry, "app_redis_dial_duration_seconds", nil, 2)
+}
@@ -0,0 +1,41 @@
+package redisv9
+
+import (
+	"context"
+	"github.com/redis/go-redis/v9"
+	"github.com/weaponry/go-instrumenting/metrics"
+	redismetrics "github.com/weaponry/go-instrumenting/metrics/redis"
+	"io"
+)
+
+// NewClusterNodeClientFunc returns function creating clients of cluster nodes with hooks passing properties of
+// commands served by the node to recorder, see redismetrics.NewClusterNodeClientFunc. Clients are created by
+// newClient, by default redis.NewClient.
+func NewClusterNodeClientFunc(recorder metrics.RedisRecorder, newClient func(opt *redis.Options) *redis.Client) func(opt *redis.Options) *redis.Client {
+	if newClient == nil {
+		newClient = redis.NewClient
+	}
+
+	observer := redismetrics.NewObserver(recorder)
+
+	return func(opt *redis.Options) *redis.Client {
+		client := newClient(opt)
+		client.AddHook(&CollectHook{observer: observer.ForNode(opt.Addr)})
+		return client
+	}
+}
+
+// WatchFailovers subscribes to '+switch-master' messages of the sentinel and passes failovers of the master with
+// passed name to recorder, see redismetrics.WatchFailovers. Watching is stopped by closing returned subscription.
+func WatchFailovers(ctx context.Context, sentinel *redis.SentinelClient, masterName string, recorder metrics.RedisRecorder) io.Closer {
+	observer := redismetrics.NewObserver(recorder)
+	pubsub := sentinel.Subscribe(ctx, "+switch-master")
+
+	go func() {
+		for msg := range pubsub.Channel() {
+			observer.ObserveSwitchMaster(masterName, msg.Payload)
+		}
+	}()
+
+	return pubsub
+}
@@ -0,0 +1,47 @@
+package redisv9_test
+
+import (
+	"context"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/redis/go-redis/v9"
+	"github.com/weaponry/go-instrumenting/metrics"
+	"github.com/weaponry/go-instrumenting/metrics/metricstest"
+	redismetrics "github.com/weaponry/go-instrumenting/metrics/redis"
+	redisv9metrics "github.com/weaponry/go-instrumenting/metrics/redis/redisv9"
+	"testing"
+)
+
+func TestNewClusterNodeClientFunc(t *testing.T) {
+	registry := prometheus.NewRegistry()
+	recorder := redismetrics.NewRedisRecorder("test-app", redismetrics.Config{
+		Options:   metrics.Options{Registerer: registry},
+		NodeLabel: true,
+	})
+
+	newClient := redisv9metrics.NewClusterNodeClientFunc(recorder, nil)
+	client := newClient(&redis.Options{Addr: "10.0.0.1:6379"})
+	defer func() { _ = client.Close() }()
+
+	client.AddHook(replyHook{err: redis.Nil})
+	_ = client.Get(context.Background(), "app/users/1").Err()
+
+	metricstest.AssertCounter(t, registry, "app_redis_requests_total", prometheus.Labels{"node": "10.0.0.1:6379", "status": "nil"}, 1)
+}
+
+// replyHook replies to commands with passed error without sending them to the server.
+type replyHook struct {
+	err error
+}
+
+func (h replyHook) DialHook(next redis.DialHook) redis.DialHook { return next }
+
+func (h replyHook) ProcessHook(redis.ProcessHook) redis.ProcessHook {
+	return func(_ context.Context, cmd redis.Cmder) error {
+		cmd.SetErr(h.err)
+		return h.err
+	}
+}
+
+func (h replyHook) ProcessPipelineHook(next redis.ProcessPipelineHook) redis.ProcessPipelineHook {
+	return next
+}
@@ -0,0 +1,126 @@
+package redis
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"github.com/weaponry/go-instrumenting/metrics"
+	"strings"
+	"sync"
+)
+
+const (
+	// scriptOther is a value of the script label used for scripts which are not registered.
+	scriptOther = "other"
+	// maxScriptBodies is the maximum number of bodies of scripts sent by EVAL which hashes are cached.
+	maxScriptBodies = 256
+)
+
+// Script is a Lua script of any supported version of go-redis, e.g. *redis.Script.
+type Script interface {
+	Hash() string
+}
+
+// scripts resolves names of scripts executed by EVAL and EVALSHA commands by their SHA1 hashes. It is safe for
+// concurrent use.
+type scripts struct {
+	mu     sync.RWMutex
+	names  map[string]string
+	hashes map[string]string
+}
+
+// newScripts creates scripts with passed names.
+func newScripts(named map[string]Script) *scripts {
+	s := &scripts{
+		names:  make(map[string]string, len(named)),
+		hashes: make(map[string]string),
+	}
+	for name, script := range named {
+		s.register(name, script)
+	}
+
+	return s
+}
+
+// register names the script.
+func (s *scripts) register(name string, script Script) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.names[strings.ToLower(script.Hash())] = name
+}
+
+// resolve returns name of the script executed by the command with passed arguments. Name is empty for other
+// commands and 'other' for scripts which are not registered.
+func (s *scripts) resolve(args []interface{}) string {
+	if len(args) < 2 {
+		return ""
+	}
+
+	var eval bool
+	switch strings.ToLower(argString(args[0])) {
+	case "eval", "eval_ro":
+		eval = true
+	case "evalsha", "evalsha_ro":
+	default:
+		return ""
+	}
+
+	s.mu.RLock()
+	named := len(s.names) > 0
+	s.mu.RUnlock()
+
+	// Hashes are not needed when there are no names.
+	if !named {
+		return scriptOther
+	}
+
+	hash := strings.ToLower(argString(args[1]))
+	if eval {
+		hash = s.hash(argString(args[1]))
+	}
+
+	s.mu.RLock()
+	name, ok := s.names[hash]
+	s.mu.RUnlock()
+
+	if !ok {
+		return scriptOther
+	}
+
+	return name
+}
+
+// hash returns SHA1 hash of the body of the script, hashes of bodies are cached up to maxScriptBodies.
+func (s *scripts) hash(body string) string {
+	s.mu.RLock()
+	hash, ok := s.hashes[body]
+	s.mu.RUnlock()
+
+	if ok {
+		return hash
+	}
+
+	sum := sha1.Sum([]byte(body))
+	hash = hex.EncodeToString(sum[:])
+
+	s.mu.Lock()
+	if len(s.hashes) < maxScriptBodies {
+		s.hashes[body] = hash
+	}
+	s.mu.Unlock()
+
+	return hash
+}
+
+// RegisterScript names executions of the script by recorder created by this package, in addition to Scripts of
+// the config. It allows naming scripts created after the recorder, e.g. in packages which receive the recorder.
+func RegisterScript(r metrics.RedisRecorder, name string, script Script) error {
+	rec, ok := r.(*recorder)
+	if !ok {
+		return fmt.Errorf("recorder of type %T doesn't name scripts", r)
+	}
+
+	rec.scripts.register(name, script)
+	return nil
+}
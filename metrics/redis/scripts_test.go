@@ -0,0 +1,122 @@
+package redis_test
+
+import (
+	"github.com/go-redis/redis/v7"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/weaponry/go-instrumenting/metrics"
+	"github.com/weaponry/go-instrumenting/metrics/metricstest"
+	redismetrics "github.com/weaponry/go-instrumenting/metrics/redis"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCollectHookScripts(t *testing.T) {
+	incr := redis.NewScript(`return redis.call('incr', KEYS[1])`)
+
+	registry := prometheus.NewRegistry()
+	recorder := redismetrics.NewRedisRecorder("test-app", redismetrics.Config{
+		Options: metrics.Options{Registerer: registry},
+		Scripts: map[string]redismetrics.Script{"incr": incr},
+	})
+
+	client := newFakeRedis(t, nil, nil)
+	client.AddHook(recorder.NewCollectHook())
+
+	// Scripts are run by EVALSHA and fall back to EVAL.
+	require.NoError(t, incr.Run(client, []string{"app/counters/1"}).Err())
+	require.NoError(t, redis.NewScript(`return 1`).Run(client, nil).Err())
+	_, err := client.Pipelined(func(pipe redis.Pipeliner) error {
+		incr.Eval(pipe, []string{"app/counters/1"})
+		return nil
+	})
+	require.NoError(t, err)
+
+	metricstest.AssertCounter(t, registry, "app_redis_script_executions_total", prometheus.Labels{"script": "incr", "status": "noscript"}, 1)
+	metricstest.AssertCounter(t, registry, "app_redis_script_executions_total", prometheus.Labels{"script": "incr", "status": "ok"}, 2)
+	metricstest.AssertCounter(t, registry, "app_redis_script_executions_total", prometheus.Labels{"script": "other", "status": "ok"}, 1)
+	metricstest.AssertCounter(t, registry, "app_redis_script_fallbacks_total", prometheus.Labels{"script": "incr"}, 1)
+	metricstest.AssertCounter(t, registry, "app_redis_script_fallbacks_total", prometheus.Labels{"script": "other"}, 1)
+
+	// Durations of scripts sent within pipelines are not known.
+	metricstest.AssertHistogramCount(t, registry, "app_redis_script_duration_seconds", prometheus.Labels{"script": "incr"}, 2)
+
+	// Keys of scripts are labelled as keys of other commands.
+	metricstest.AssertCounter(t, registry, "app_redis_requests_total", prometheus.Labels{"command": "evalsha", "keyspace": "/counters"}, 1)
+}
+
+func TestObserverScripts(t *testing.T) {
+	incr := redis.NewScript(`return redis.call('incr', KEYS[1])`)
+
+	testCases := []struct {
+		name      string
+		cmd       *redis.Cmd
+		expScript string
+	}{
+		{
+			name:      "Scripts should be named by their hashes.",
+			cmd:       redis.NewCmd("evalsha", incr.Hash(), 1, "app/counters/1"),
+			expScript: "incr",
+		},
+		{
+			name:      "Hashes should be case insensitive.",
+			cmd:       redis.NewCmd("EVALSHA_RO", strings.ToUpper(incr.Hash()), 0),
+			expScript: "incr",
+		},
+		{
+			name:      "Scripts sent by EVAL should be named by their sources.",
+			cmd:       redis.NewCmd("eval", `return redis.call('incr', KEYS[1])`, 1, "app/counters/1"),
+			expScript: "incr",
+		},
+		{
+			name:      "Unknown scripts should be labelled as other.",
+			cmd:       redis.NewCmd("eval_ro", `return 1`, 0),
+			expScript: "other",
+		},
+		{
+			name: "Other commands should not be labelled by scripts.",
+			cmd:  redis.NewCmd("get", "app/counters/1"),
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			registry := prometheus.NewRegistry()
+			recorder := redismetrics.NewRedisRecorder("test-app", redismetrics.Config{
+				Options: metrics.Options{Registerer: registry},
+				Scripts: map[string]redismetrics.Script{"incr": incr},
+			})
+
+			redismetrics.NewObserver(recorder).ObserveCommand(tc.cmd, time.Millisecond)
+
+			expScripts := []string{}
+			if tc.expScript != "" {
+				expScripts = []string{tc.expScript}
+			}
+			assert.Equal(t, expScripts, metricstest.LabelValues(t, registry, "app_redis_script_executions_total", "script"))
+		})
+	}
+}
+
+func TestRegisterScript(t *testing.T) {
+	incr := redis.NewScript(`return redis.call('incr', KEYS[1])`)
+
+	registry := prometheus.NewRegistry()
+	recorder := redismetrics.NewRedisRecorder("test-app", redismetrics.Config{Options: metrics.Options{Registerer: registry}})
+	observer := redismetrics.NewObserver(recorder)
+
+	// Scripts should be labelled as other until they are registered.
+	observer.ObserveCommand(redis.NewCmd("evalsha", incr.Hash(), 1, "app/counters/1"), time.Millisecond)
+	require.NoError(t, redismetrics.RegisterScript(recorder, "incr", incr))
+	observer.ObserveCommand(redis.NewCmd("evalsha", incr.Hash(), 1, "app/counters/1"), time.Millisecond)
+	observer.ObserveCommand(redis.NewCmd("eval", `return redis.call('incr', KEYS[1])`, 1, "app/counters/1"), time.Millisecond)
+	observer.ObserveCommand(redis.NewCmd("eval", `return redis.call('incr', KEYS[1])`, 1, "app/counters/1"), time.Millisecond)
+
+	metricstest.AssertCounter(t, registry, "app_redis_script_executions_total", prometheus.Labels{"script": "other"}, 1)
+	metricstest.AssertCounter(t, registry, "app_redis_script_executions_total", prometheus.Labels{"script": "incr"}, 3)
+
+	// Scripts can't be registered in recorders created by other packages.
+	assert.Error(t, redismetrics.RegisterScript(metricstest.NewRedisRecorder(), "incr", incr))
+}
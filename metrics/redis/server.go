@@ -0,0 +1,385 @@
+package redis
+
+import (
+	"context"
+	"fmt"
+	"github.com/go-redis/redis/v7"
+	"github.com/prometheus/client_golang/prometheus"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const labelReplica = "replica"
+
+// serverStatsCollector exports statistics of Redis servers reported by INFO and SLOWLOG GET commands. Commands are
+// sent in the background when a scrape finds results older than maxAge, the scrape waits for them at most timeout
+// and exports the latest results otherwise.
+type serverStatsCollector struct {
+	forEachNode    func(fn func(client *redis.Client) error) error
+	maxAge         time.Duration
+	timeout        time.Duration
+	slowlogEntries int
+
+	mu         sync.Mutex
+	refreshed  time.Time
+	refreshing chan struct{} // closed when the running refresh is done, nil if no refresh is running
+	nodes      map[string]*serverStats
+
+	usedMemory       *prometheus.Desc
+	maxMemory        *prometheus.Desc
+	connectedClients *prometheus.Desc
+	evictedKeys      *prometheus.Desc
+	keyspaceHits     *prometheus.Desc
+	keyspaceMisses   *prometheus.Desc
+	replOffset       *prometheus.Desc
+	replicaLagBytes  *prometheus.Desc
+	replicaLag       *prometheus.Desc
+	slowlogTotal     *prometheus.Desc
+	slowlogDuration  *prometheus.Desc
+}
+
+// serverStats are statistics of the node.
+type serverStats struct {
+	// info are fields of the latest INFO reply, nil if the latest INFO has failed.
+	info map[string]string
+	// slowlogID is ID of the latest counted entry of the slowlog, -1 if no entries have been counted.
+	slowlogID int64
+	// slowlog are counted entries of the slowlog by commands.
+	slowlog map[string]*slowlogStats
+}
+
+// slowlogStats are statistics of slowlog entries of the command.
+type slowlogStats struct {
+	entries  int
+	duration time.Duration
+}
+
+// NewServerStatsCollector creates collector of server statistics and registers it, it panics if registration fails.
+func NewServerStatsCollector(appName string, client redis.UniversalClient, config Config) prometheus.Collector {
+	c, err := RegisterServerStatsCollector(appName, client, config)
+	if err != nil {
+		panic(err)
+	}
+	return c
+}
+
+// RegisterServerStatsCollector creates collector of server statistics and registers it. Statistics are requested by
+// INFO and SLOWLOG GET commands sent by client at scrape time, hence the commands are measured by hooks of the client
+// as other commands. Client could be *redis.Client, *redis.ClusterClient or *redis.Ring, statistics are labelled by
+// address of the node (shard of the ring). Results of commands are reused by scrapes for ServerStatsMaxAge of config.
+// Nodes of the cluster are listed at most once a minute, as listing reloads state of the cluster by CLUSTER SLOTS
+// command, nodes added to the cluster meanwhile are exported by later refreshes. Commands are bounded by ServerStatsTimeout of config, scrapes don't wait for slow nodes longer and export the latest
+// results of them. Collector could be removed with Unregister of config's Registerer.
+func RegisterServerStatsCollector(appName string, client redis.UniversalClient, config Config) (prometheus.Collector, error) {
+	config.defaults()
+
+	if err := config.Validate(labelNode, labelCommand, labelReplica); err != nil {
+		return nil, err
+	}
+
+	c := &serverStatsCollector{
+		maxAge:         config.ServerStatsMaxAge,
+		timeout:        config.ServerStatsTimeout,
+		slowlogEntries: config.SlowlogEntries,
+		nodes:          make(map[string]*serverStats),
+	}
+
+	switch v := client.(type) {
+	case *redis.Client:
+		c.forEachNode = func(fn func(client *redis.Client) error) error { return fn(v) }
+	case *redis.ClusterClient:
+		c.forEachNode = (&clusterNodes{client: v}).forEachNode
+	case *redis.Ring:
+		c.forEachNode = v.ForEachShard
+	default:
+		return nil, fmt.Errorf("unsupported client type %T", client)
+	}
+
+	constLabels := config.Labels(appName)
+	desc := func(name, help string, labels ...string) *prometheus.Desc {
+		return prometheus.NewDesc(prometheus.BuildFQName(config.Namespace, config.Subsystem, name), help, append([]string{labelNode}, labels...), constLabels)
+	}
+
+	c.usedMemory = desc("server_used_memory_bytes", "The number of bytes allocated by Redis.")
+	c.maxMemory = desc("server_max_memory_bytes", "The maximum memory of Redis, zero if it is not limited.")
+	c.connectedClients = desc("server_connected_clients", "The number of client connections to Redis.")
+	c.evictedKeys = desc("server_evicted_keys_total", "The total number of keys evicted due to the maxmemory limit.")
+	c.keyspaceHits = desc("server_keyspace_hits_total", "The total number of successful lookups of keys in the main dictionary.")
+	c.keyspaceMisses = desc("server_keyspace_misses_total", "The total number of failed lookups of keys in the main dictionary.")
+	c.replOffset = desc("server_replication_offset_bytes", "The replication offset of the node.")
+	c.replicaLagBytes = desc("server_replica_lag_bytes", "The difference of replication offsets of the master and its replica.", labelReplica)
+	c.replicaLag = desc("server_replica_lag_seconds", "The time since the latest acknowledgement of the replica received by the master.", labelReplica)
+	c.slowlogTotal = desc("server_slowlog_entries_total", "The total number of commands logged by the slowlog.", labelCommand)
+	c.slowlogDuration = desc("server_slowlog_duration_seconds_total", "The total execution time of commands logged by the slowlog.", labelCommand)
+
+	// Collectors of different clients can't be shared, hence AlreadyRegisteredError is returned as is.
+	if err := config.Registerer.Register(c); err != nil {
+		return nil, err
+	}
+
+	return c, nil
+}
+
+// Describe implements prometheus.Collector.
+func (c *serverStatsCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.usedMemory
+	ch <- c.maxMemory
+	ch <- c.connectedClients
+	ch <- c.evictedKeys
+	ch <- c.keyspaceHits
+	ch <- c.keyspaceMisses
+	ch <- c.replOffset
+	ch <- c.replicaLagBytes
+	ch <- c.replicaLag
+	ch <- c.slowlogTotal
+	ch <- c.slowlogDuration
+}
+
+// Collect implements prometheus.Collector.
+func (c *serverStatsCollector) Collect(ch chan<- prometheus.Metric) {
+	if done := c.startRefresh(); done != nil {
+		timer := time.NewTimer(c.timeout)
+		select {
+		case <-done:
+		case <-timer.C:
+		}
+		timer.Stop()
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for node, stats := range c.nodes {
+		c.collectInfo(ch, node, stats.info)
+
+		for command, slowlog := range stats.slowlog {
+			ch <- prometheus.MustNewConstMetric(c.slowlogTotal, prometheus.CounterValue, float64(slowlog.entries), node, command)
+			ch <- prometheus.MustNewConstMetric(c.slowlogDuration, prometheus.CounterValue, slowlog.duration.Seconds(), node, command)
+		}
+	}
+}
+
+// startRefresh starts refresh of results older than maxAge unless a refresh is already running. It returns channel
+// closed when the running refresh is done, or nil if results are fresh.
+func (c *serverStatsCollector) startRefresh() <-chan struct{} {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.refreshing == nil && time.Since(c.refreshed) >= c.maxAge {
+		done := make(chan struct{})
+		c.refreshing = done
+		c.refreshed = time.Now()
+
+		go func() {
+			defer close(done)
+			c.refresh()
+
+			c.mu.Lock()
+			c.refreshing = nil
+			c.mu.Unlock()
+		}()
+	}
+
+	return c.refreshing
+}
+
+// refresh requests INFO and SLOWLOG GET from all nodes within timeout, results are stored as soon as they are
+// received. INFO of nodes which are not iterated anymore (e.g. removed from the cluster) is forgotten, counted slowlog
+// entries are kept.
+func (c *serverStatsCollector) refresh() {
+	ctx, cancel := context.WithTimeout(context.Background(), c.timeout)
+	defer cancel()
+
+	// Nodes are iterated concurrently by cluster and ring clients.
+	seen := make(map[string]bool)
+
+	_ = c.forEachNode(func(client *redis.Client) error {
+		node := client.Options().Addr
+		client = client.WithContext(ctx)
+		info, err := client.Info().Result()
+		slowlog := client.Do("slowlog", "get", c.slowlogEntries)
+
+		c.mu.Lock()
+		defer c.mu.Unlock()
+
+		seen[node] = true
+		stats, ok := c.nodes[node]
+		if !ok {
+			stats = &serverStats{slowlogID: -1, slowlog: make(map[string]*slowlogStats)}
+			c.nodes[node] = stats
+		}
+
+		stats.info = nil
+		if err == nil {
+			stats.info = parseInfo(info)
+		}
+		if entries, ok := slowlog.Val().([]interface{}); ok && slowlog.Err() == nil {
+			stats.countSlowlog(entries)
+		}
+		return nil
+	})
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for node, stats := range c.nodes {
+		if !seen[node] {
+			stats.info = nil
+		}
+	}
+}
+
+// clusterNodesMaxAge is the maximum age of the listed nodes of the cluster, go-redis reloads state of the cluster
+// lazily once a minute too.
+const clusterNodesMaxAge = time.Minute
+
+// clusterNodes iterates nodes of the cluster. ForEachNode of go-redis v7 reloads state of the cluster on every call,
+// hence nodes are listed by it at most once per clusterNodesMaxAge and the listed clients of nodes are iterated
+// between listings. Nodes are iterated by one refresh at a time.
+type clusterNodes struct {
+	client  *redis.ClusterClient
+	listed  time.Time
+	clients []*redis.Client
+}
+
+// forEachNode concurrently calls fn for each node of the cluster, fn of cached nodes never fails.
+func (n *clusterNodes) forEachNode(fn func(client *redis.Client) error) error {
+	if time.Since(n.listed) >= clusterNodesMaxAge {
+		var mu sync.Mutex
+		var clients []*redis.Client
+		err := n.client.ForEachNode(func(client *redis.Client) error {
+			mu.Lock()
+			clients = append(clients, client)
+			mu.Unlock()
+			return fn(client)
+		})
+		if err == nil {
+			n.listed = time.Now()
+			n.clients = clients
+		}
+		return err
+	}
+
+	var wg sync.WaitGroup
+	for _, client := range n.clients {
+		wg.Add(1)
+		go func(client *redis.Client) {
+			defer wg.Done()
+			_ = fn(client)
+		}(client)
+	}
+	wg.Wait()
+	return nil
+}
+
+// collectInfo exports fields of INFO reply of the node.
+func (c *serverStatsCollector) collectInfo(ch chan<- prometheus.Metric, node string, info map[string]string) {
+	if info == nil {
+		return
+	}
+
+	export := func(desc *prometheus.Desc, typ prometheus.ValueType, field string) {
+		if v, err := strconv.ParseFloat(info[field], 64); err == nil {
+			ch <- prometheus.MustNewConstMetric(desc, typ, v, node)
+		}
+	}
+
+	export(c.usedMemory, prometheus.GaugeValue, "used_memory")
+	export(c.maxMemory, prometheus.GaugeValue, "maxmemory")
+	export(c.connectedClients, prometheus.GaugeValue, "connected_clients")
+	export(c.evictedKeys, prometheus.CounterValue, "evicted_keys")
+	export(c.keyspaceHits, prometheus.CounterValue, "keyspace_hits")
+	export(c.keyspaceMisses, prometheus.CounterValue, "keyspace_misses")
+	export(c.replOffset, prometheus.GaugeValue, "master_repl_offset")
+
+	// Replicas of the master are listed as 'slave<N>:ip=...,port=...,state=...,offset=...,lag=...'.
+	masterOffset, err := strconv.ParseInt(info["master_repl_offset"], 10, 64)
+	if err != nil {
+		return
+	}
+	for field, value := range info {
+		if !strings.HasPrefix(field, "slave") {
+			continue
+		}
+		if _, err := strconv.Atoi(field[len("slave"):]); err != nil {
+			continue
+		}
+
+		replica := parseInfoValues(value)
+		addr := net.JoinHostPort(replica["ip"], replica["port"])
+		if offset, err := strconv.ParseInt(replica["offset"], 10, 64); err == nil {
+			ch <- prometheus.MustNewConstMetric(c.replicaLagBytes, prometheus.GaugeValue, float64(masterOffset-offset), node, addr)
+		}
+		if lag, err := strconv.ParseFloat(replica["lag"], 64); err == nil {
+			ch <- prometheus.MustNewConstMetric(c.replicaLag, prometheus.GaugeValue, lag, node, addr)
+		}
+	}
+}
+
+// countSlowlog counts entries of SLOWLOG GET reply which have not been counted yet. Entries are replied from the
+// latest one as '[id, timestamp, duration in microseconds, [command, args...], ...]'.
+func (s *serverStats) countSlowlog(entries []interface{}) {
+	var latestID int64 = -1
+	for i, entry := range entries {
+		fields, _ := entry.([]interface{})
+		if len(fields) < 4 {
+			continue
+		}
+		id, _ := fields[0].(int64)
+		duration, _ := fields[2].(int64)
+		args, _ := fields[3].([]interface{})
+
+		if i == 0 {
+			latestID = id
+			// IDs are restarted by restarts of the server.
+			if id < s.slowlogID {
+				s.slowlogID = -1
+			}
+		}
+		if id <= s.slowlogID || len(args) == 0 {
+			continue
+		}
+
+		command := strings.ToLower(argString(args[0]))
+		stats, ok := s.slowlog[command]
+		if !ok {
+			stats = &slowlogStats{}
+			s.slowlog[command] = stats
+		}
+		stats.entries++
+		stats.duration += time.Duration(duration) * time.Microsecond
+	}
+
+	if latestID > s.slowlogID {
+		s.slowlogID = latestID
+	}
+}
+
+// parseInfo returns fields of INFO reply, sections are ignored.
+func parseInfo(info string) map[string]string {
+	fields := make(map[string]string)
+	for _, line := range strings.Split(info, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if i := strings.IndexByte(line, ':'); i > 0 {
+			fields[line[:i]] = line[i+1:]
+		}
+	}
+	return fields
+}
+
+// parseInfoValues returns values of INFO field formatted as 'key1=value1,key2=value2'.
+func parseInfoValues(value string) map[string]string {
+	values := make(map[string]string)
+	for _, pair := range strings.Split(value, ",") {
+		if i := strings.IndexByte(pair, '='); i > 0 {
+			values[pair[:i]] = pair[i+1:]
+		}
+	}
+	return values
+}
@@ -0,0 +1,264 @@
+package redis_test
+
+import (
+	"fmt"
+	"github.com/go-redis/redis/v7"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/weaponry/go-instrumenting/metrics"
+	"github.com/weaponry/go-instrumenting/metrics/metricstest"
+	redismetrics "github.com/weaponry/go-instrumenting/metrics/redis"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeServer is a state of Redis server replied to INFO and SLOWLOG GET commands.
+type fakeServer struct {
+	mu      sync.Mutex
+	info    string
+	slowlog []fakeSlowlogEntry
+	calls   int
+}
+
+// fakeSlowlogEntry is an entry of the slowlog.
+type fakeSlowlogEntry struct {
+	id       int
+	duration time.Duration
+	args     []string
+}
+
+func (s *fakeServer) reply(args []string) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	switch strings.ToLower(args[0]) {
+	case "info":
+		s.calls++
+		return fmt.Sprintf("$%d\r\n%s\r\n", len(s.info), s.info)
+	case "slowlog":
+		r := fmt.Sprintf("*%d\r\n", len(s.slowlog))
+		for i := len(s.slowlog) - 1; i >= 0; i-- {
+			entry := s.slowlog[i]
+			r += fmt.Sprintf("*6\r\n:%d\r\n:1600000000\r\n:%d\r\n*%d\r\n", entry.id, entry.duration.Microseconds(), len(entry.args))
+			for _, arg := range entry.args {
+				r += bulk(arg)
+			}
+			r += bulk("127.0.0.1:50000") + bulk("")
+		}
+		return r
+	default:
+		return "-ERR unknown command\r\n"
+	}
+}
+
+func (s *fakeServer) log(entries ...fakeSlowlogEntry) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.slowlog = append(s.slowlog, entries...)
+}
+
+func (s *fakeServer) restart() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.slowlog = nil
+}
+
+func TestRegisterServerStatsCollector(t *testing.T) {
+	server := &fakeServer{info: strings.Join([]string{
+		"# Memory",
+		"used_memory:1048576",
+		"maxmemory:0",
+		"# Clients",
+		"connected_clients:12",
+		"# Stats",
+		"evicted_keys:3",
+		"keyspace_hits:40",
+		"keyspace_misses:10",
+		"# Replication",
+		"role:master",
+		"master_repl_offset:1000",
+	}, "\r\n")}
+	server.log(
+		fakeSlowlogEntry{id: 0, duration: 20 * time.Millisecond, args: []string{"KEYS", "*"}},
+		fakeSlowlogEntry{id: 1, duration: 10 * time.Millisecond, args: []string{"hgetall", "app/users/1"}},
+	)
+
+	registry := prometheus.NewRegistry()
+	recorder := redismetrics.NewRedisRecorder("test-app", redismetrics.Config{Options: metrics.Options{Registerer: registry}})
+
+	client := redis.NewClient(&redis.Options{
+		Addr:   "10.0.0.1:6379",
+		Dialer: newFakeDialer(func(_ string, args []string) string { return server.reply(args) }),
+	})
+	defer func() { _ = client.Close() }()
+	client.AddHook(recorder.NewCollectHook())
+
+	_, err := redismetrics.RegisterServerStatsCollector("test-app", client, redismetrics.Config{
+		Options:           metrics.Options{Registerer: registry},
+		ServerStatsMaxAge: time.Nanosecond,
+	})
+	require.NoError(t, err)
+
+	node := prometheus.Labels{"node": "10.0.0.1:6379"}
+	metricstest.AssertGauge(t, registry, "app_redis_server_used_memory_bytes", node, 1048576)
+	metricstest.AssertGauge(t, registry, "app_redis_server_max_memory_bytes", node, 0)
+	metricstest.AssertGauge(t, registry, "app_redis_server_connected_clients", node, 12)
+	metricstest.AssertCounter(t, registry, "app_redis_server_evicted_keys_total", node, 3)
+	metricstest.AssertCounter(t, registry, "app_redis_server_keyspace_hits_total", node, 40)
+	metricstest.AssertCounter(t, registry, "app_redis_server_keyspace_misses_total", node, 10)
+	metricstest.AssertGauge(t, registry, "app_redis_server_replication_offset_bytes", node, 1000)
+	metricstest.AssertCounter(t, registry, "app_redis_server_slowlog_entries_total", prometheus.Labels{"command": "keys"}, 1)
+	metricstest.AssertCounter(t, registry, "app_redis_server_slowlog_entries_total", prometheus.Labels{"command": "hgetall"}, 1)
+
+	// Commands are sent through the instrumented client by each scrape.
+	assert.NotZero(t, metricstest.CounterValue(t, registry, "app_redis_requests_total", prometheus.Labels{"command": "info"}))
+	assert.NotZero(t, metricstest.CounterValue(t, registry, "app_redis_requests_total", prometheus.Labels{"command": "slowlog"}))
+
+	// Only new entries of the slowlog should be counted.
+	server.log(fakeSlowlogEntry{id: 2, duration: 30 * time.Millisecond, args: []string{"hgetall", "app/users/2"}})
+	metricstest.AssertCounter(t, registry, "app_redis_server_slowlog_entries_total", prometheus.Labels{"command": "hgetall"}, 2)
+	assert.InDelta(t, 0.04, metricstest.CounterValue(t, registry, "app_redis_server_slowlog_duration_seconds_total", prometheus.Labels{"command": "hgetall"}), 1e-9)
+
+	// Entries logged after restarts of the server should be counted.
+	server.restart()
+	server.log(fakeSlowlogEntry{id: 0, duration: 10 * time.Millisecond, args: []string{"keys", "app/*"}})
+	metricstest.AssertCounter(t, registry, "app_redis_server_slowlog_entries_total", prometheus.Labels{"command": "keys"}, 2)
+	metricstest.AssertCounter(t, registry, "app_redis_server_slowlog_entries_total", prometheus.Labels{"command": "hgetall"}, 2)
+}
+
+func TestServerStatsCollectorMaxAge(t *testing.T) {
+	server := &fakeServer{info: "connected_clients:1"}
+
+	client := redis.NewClient(&redis.Options{
+		Dialer: newFakeDialer(func(_ string, args []string) string { return server.reply(args) }),
+	})
+	defer func() { _ = client.Close() }()
+
+	registry := prometheus.NewRegistry()
+	_, err := redismetrics.RegisterServerStatsCollector("test-app", client, redismetrics.Config{Options: metrics.Options{Registerer: registry}})
+	require.NoError(t, err)
+
+	// Results of commands should be reused by scrapes.
+	metricstest.AssertGauge(t, registry, "app_redis_server_connected_clients", nil, 1)
+	server.mu.Lock()
+	server.info = "connected_clients:2"
+	server.mu.Unlock()
+	metricstest.AssertGauge(t, registry, "app_redis_server_connected_clients", nil, 1)
+	assert.Equal(t, 1, server.calls)
+
+	// Constant labels should not collide with labels of the collector.
+	_, err = redismetrics.RegisterServerStatsCollector("test-app", client, redismetrics.Config{
+		Options: metrics.Options{ConstLabels: prometheus.Labels{"command": "info"}, Registerer: prometheus.NewRegistry()},
+	})
+	assert.Error(t, err)
+}
+
+func TestServerStatsCollectorCluster(t *testing.T) {
+	servers := map[string]*fakeServer{
+		"10.0.0.1:6379": {info: "role:master\r\nmaster_repl_offset:1000\r\nconnected_slaves:1\r\n" +
+			"slave0:ip=10.0.0.2,port=6379,state=online,offset=900,lag=1\r\n"},
+		"10.0.0.2:6379": {info: "role:slave\r\nmaster_repl_offset:900\r\n"},
+	}
+
+	cluster := redis.NewClusterClient(&redis.ClusterOptions{
+		ClusterSlots: func() ([]redis.ClusterSlot, error) {
+			return []redis.ClusterSlot{{
+				Start: 0,
+				End:   16383,
+				Nodes: []redis.ClusterNode{{Addr: "10.0.0.1:6379"}, {Addr: "10.0.0.2:6379"}},
+			}}, nil
+		},
+		Dialer: newFakeDialer(func(addr string, args []string) string { return servers[addr].reply(args) }),
+	})
+	defer func() { _ = cluster.Close() }()
+
+	registry := prometheus.NewRegistry()
+	_, err := redismetrics.RegisterServerStatsCollector("test-app", cluster, redismetrics.Config{Options: metrics.Options{Registerer: registry}})
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{"10.0.0.1:6379", "10.0.0.2:6379"}, metricstest.LabelValues(t, registry, "app_redis_server_replication_offset_bytes", "node"))
+
+	replica := prometheus.Labels{"node": "10.0.0.1:6379", "replica": "10.0.0.2:6379"}
+	metricstest.AssertGauge(t, registry, "app_redis_server_replica_lag_bytes", replica, 100)
+	metricstest.AssertGauge(t, registry, "app_redis_server_replica_lag_seconds", replica, 1)
+}
+
+func TestServerStatsCollectorClusterReloads(t *testing.T) {
+	server := &fakeServer{info: "connected_clients:1"}
+
+	var mu sync.Mutex
+	reloads := 0
+	cluster := redis.NewClusterClient(&redis.ClusterOptions{
+		ClusterSlots: func() ([]redis.ClusterSlot, error) {
+			mu.Lock()
+			defer mu.Unlock()
+			reloads++
+			return []redis.ClusterSlot{{Start: 0, End: 16383, Nodes: []redis.ClusterNode{{Addr: "10.0.0.1:6379"}}}}, nil
+		},
+		Dialer: newFakeDialer(func(_ string, args []string) string { return server.reply(args) }),
+	})
+	defer func() { _ = cluster.Close() }()
+
+	registry := prometheus.NewRegistry()
+	_, err := redismetrics.RegisterServerStatsCollector("test-app", cluster, redismetrics.Config{
+		Options:           metrics.Options{Registerer: registry},
+		ServerStatsMaxAge: time.Nanosecond,
+	})
+	require.NoError(t, err)
+
+	metricstest.AssertGauge(t, registry, "app_redis_server_connected_clients", nil, 1)
+	mu.Lock()
+	listed := reloads
+	mu.Unlock()
+
+	// Refreshes should reuse the listed nodes instead of reloading state of the cluster.
+	for i := 0; i < 3; i++ {
+		metricstest.AssertGauge(t, registry, "app_redis_server_connected_clients", nil, 1)
+	}
+	assert.Equal(t, 4, server.calls)
+	mu.Lock()
+	assert.Equal(t, listed, reloads)
+	mu.Unlock()
+}
+
+func TestServerStatsCollectorTimeout(t *testing.T) {
+	server := &fakeServer{info: "connected_clients:1"}
+	hung := make(chan struct{})
+	defer close(hung)
+
+	cluster := redis.NewClusterClient(&redis.ClusterOptions{
+		ClusterSlots: func() ([]redis.ClusterSlot, error) {
+			return []redis.ClusterSlot{
+				{Start: 0, End: 8191, Nodes: []redis.ClusterNode{{Addr: "10.0.0.1:6379"}}},
+				{Start: 8192, End: 16383, Nodes: []redis.ClusterNode{{Addr: "10.0.0.2:6379"}}},
+			}, nil
+		},
+		Dialer: newFakeDialer(func(addr string, args []string) string {
+			if addr == "10.0.0.2:6379" {
+				<-hung
+			}
+			return server.reply(args)
+		}),
+	})
+	defer func() { _ = cluster.Close() }()
+
+	registry := prometheus.NewRegistry()
+	_, err := redismetrics.RegisterServerStatsCollector("test-app", cluster, redismetrics.Config{
+		Options:            metrics.Options{Registerer: registry},
+		ServerStatsMaxAge:  time.Nanosecond,
+		ServerStatsTimeout: 50 * time.Millisecond,
+	})
+	require.NoError(t, err)
+
+	// Scrapes should not wait for hung nodes longer than the timeout, results of other nodes should be exported.
+	for i := 0; i < 3; i++ {
+		start := time.Now()
+		assert.Equal(t, []string{"10.0.0.1:6379"}, metricstest.LabelValues(t, registry, "app_redis_server_connected_clients", "node"))
+		assert.Less(t, int64(time.Since(start)), int64(time.Second))
+	}
+}
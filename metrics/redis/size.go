@@ -0,0 +1,206 @@
+package redis
+
+import (
+	"reflect"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// timeType is the type of replies of TIME command.
+var timeType = reflect.TypeOf(time.Time{})
+
+// valMethods caches indexes of Val methods of command types which replies are measured by reflection, or -1 if
+// the type has no such method. Types of commands are known at compile time, hence the cache is bounded.
+var valMethods sync.Map // map[reflect.Type]int
+
+// requestSize estimates size of the command as a sum of sizes of its arguments.
+func requestSize(cmd Cmder) int {
+	var size int
+	for _, arg := range cmd.Args() {
+		size += valueSize(arg)
+	}
+	return size
+}
+
+// replySize estimates size of the reply of the command as a sum of sizes of its strings, bulk strings and elements of
+// arrays, it returns -1 if the reply has not been received because of the error or the type of the reply is unknown.
+func replySize(cmd Cmder) int {
+	if err := cmd.Err(); err != nil {
+		if isNil(err) {
+			return 0
+		}
+		return -1
+	}
+
+	switch c := cmd.(type) {
+	case cmdReply:
+		return valueSize(c.Val())
+	case stringReply:
+		return len(c.Val())
+	case sliceReply:
+		return valueSize(c.Val())
+	case stringsReply:
+		var size int
+		for _, v := range c.Val() {
+			size += len(v)
+		}
+		return size
+	case stringMapReply:
+		var size int
+		for k, v := range c.Val() {
+			size += len(k) + len(v)
+		}
+		return size
+	case intReply:
+		return valueSize(c.Val())
+	case boolReply:
+		// Booleans are replied as integers 0 or 1.
+		return 1
+	case floatReply:
+		return valueSize(c.Val())
+	case scanReply:
+		keys, cursor := c.Val()
+		size := len(strconv.FormatUint(cursor, 10))
+		for _, key := range keys {
+			size += len(key)
+		}
+		return size
+	default:
+		// Replies of structured types (e.g. sorted set members with scores or stream messages) differ between
+		// versions of go-redis, they are measured by their fields.
+		return reflectReplySize(cmd)
+	}
+}
+
+// reflectReplySize estimates size of the value returned by Val method of the command, it returns -1 if the command
+// has no such method.
+func reflectReplySize(cmd Cmder) int {
+	v := reflect.ValueOf(cmd)
+	i := valMethod(v.Type())
+	if i < 0 {
+		return -1
+	}
+
+	var size int
+	for _, out := range v.Method(i).Call(nil) {
+		n := reflectSize(out)
+		if n < 0 {
+			return -1
+		}
+		size += n
+	}
+	return size
+}
+
+// valMethod returns index of Val method of the command type without arguments, or -1 if the type has no such method.
+func valMethod(t reflect.Type) int {
+	if i, ok := valMethods.Load(t); ok {
+		return i.(int)
+	}
+
+	i := -1
+	// Methods of concrete types take the receiver as the first argument.
+	if m, ok := t.MethodByName("Val"); ok && m.Type.NumIn() == 1 && m.Type.NumOut() > 0 {
+		i = m.Index
+	}
+	valMethods.Store(t, i)
+
+	return i
+}
+
+// reflectSize returns size of the value as it is sent by Redis protocol, or -1 if the size is unknown.
+func reflectSize(v reflect.Value) int {
+	switch v.Kind() {
+	case reflect.Invalid:
+		return 0
+	case reflect.String:
+		return v.Len()
+	case reflect.Bool:
+		return 1
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return len(strconv.FormatInt(v.Int(), 10))
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return len(strconv.FormatUint(v.Uint(), 10))
+	case reflect.Float32, reflect.Float64:
+		return len(strconv.FormatFloat(v.Float(), 'f', -1, 64))
+	case reflect.Interface, reflect.Ptr:
+		if v.IsNil() {
+			return 0
+		}
+		return reflectSize(v.Elem())
+	case reflect.Slice, reflect.Array:
+		if v.Type().Elem().Kind() == reflect.Uint8 {
+			return v.Len()
+		}
+		var size int
+		for i := 0; i < v.Len(); i++ {
+			n := reflectSize(v.Index(i))
+			if n < 0 {
+				return -1
+			}
+			size += n
+		}
+		return size
+	case reflect.Map:
+		var size int
+		iter := v.MapRange()
+		for iter.Next() {
+			k, e := reflectSize(iter.Key()), reflectSize(iter.Value())
+			if k < 0 || e < 0 {
+				return -1
+			}
+			size += k + e
+		}
+		return size
+	case reflect.Struct:
+		if v.Type() == timeType && v.CanInterface() {
+			// Time is replied as an array of seconds and microseconds.
+			t := v.Interface().(time.Time)
+			return len(strconv.FormatInt(t.Unix(), 10)) + len(strconv.Itoa(t.Nanosecond()/1000))
+		}
+		var size int
+		for i := 0; i < v.NumField(); i++ {
+			n := reflectSize(v.Field(i))
+			if n < 0 {
+				return -1
+			}
+			size += n
+		}
+		return size
+	default:
+		return -1
+	}
+}
+
+// valueSize returns size of the value as it is sent by Redis protocol.
+func valueSize(v interface{}) int {
+	switch v := v.(type) {
+	case nil:
+		return 0
+	case string:
+		return len(v)
+	case []byte:
+		return len(v)
+	case int64:
+		return len(strconv.FormatInt(v, 10))
+	case int:
+		return len(strconv.Itoa(v))
+	case float64:
+		return len(strconv.FormatFloat(v, 'f', -1, 64))
+	case []interface{}:
+		var size int
+		for _, e := range v {
+			size += valueSize(e)
+		}
+		return size
+	case map[interface{}]interface{}:
+		var size int
+		for k, e := range v {
+			size += valueSize(k) + valueSize(e)
+		}
+		return size
+	default:
+		return len(argString(v))
+	}
+}
@@ -0,0 +1,253 @@
+package redis_test
+
+import (
+	"fmt"
+	"github.com/go-redis/redis/v7"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/weaponry/go-instrumenting/metrics"
+	"github.com/weaponry/go-instrumenting/metrics/metricstest"
+	redismetrics "github.com/weaponry/go-instrumenting/metrics/redis"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCollectHookSizes(t *testing.T) {
+	strs := map[string]string{"app/users/1": "alice", "app/users/2": "bob"}
+	hashes := map[string]map[string]string{"app/profiles/1": {"name": "alice"}}
+
+	testCases := []struct {
+		name           string
+		run            func(client *redis.Client)
+		command        string
+		expRequestSize float64
+		expReplySize   float64
+		expReplies     uint64
+	}{
+		{
+			name: "Sizes of bulk strings should be estimated.",
+			run: func(client *redis.Client) {
+				client.Get("app/users/1")
+			},
+			command:        "get",
+			expRequestSize: 14,
+			expReplySize:   5,
+			expReplies:     1,
+		},
+		{
+			name: "Missing values should have empty replies.",
+			run: func(client *redis.Client) {
+				client.Get("app/users/3")
+			},
+			command:        "get",
+			expRequestSize: 14,
+			expReplySize:   0,
+			expReplies:     1,
+		},
+		{
+			name: "Sizes of arrays should be sums of their elements.",
+			run: func(client *redis.Client) {
+				client.MGet("app/users/1", "app/users/2", "app/users/3")
+			},
+			command:        "mget",
+			expRequestSize: 37,
+			expReplySize:   8,
+			expReplies:     1,
+		},
+		{
+			name: "Sizes of maps should be sums of their keys and values.",
+			run: func(client *redis.Client) {
+				client.HGetAll("app/profiles/1")
+			},
+			command:        "hgetall",
+			expRequestSize: 21,
+			expReplySize:   9,
+			expReplies:     1,
+		},
+		{
+			name: "Replies of failed commands should not be measured.",
+			run: func(client *redis.Client) {
+				client.Do("echo", "hello")
+			},
+			command:        "echo",
+			expRequestSize: 9,
+			expReplies:     0,
+		},
+		{
+			name: "Commands of pipelines should be measured.",
+			run: func(client *redis.Client) {
+				pipe := client.Pipeline()
+				pipe.Get("app/users/1")
+				pipe.Get("app/users/2")
+				_, _ = pipe.Exec()
+			},
+			command:        "get",
+			expRequestSize: 28,
+			expReplySize:   8,
+			expReplies:     2,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			registry := prometheus.NewRegistry()
+			recorder := redismetrics.NewRedisRecorder("test-app", redismetrics.Config{Options: metrics.Options{Registerer: registry}})
+
+			client := newFakeRedis(t, strs, hashes)
+			client.AddHook(recorder.NewCollectHook())
+			tc.run(client)
+
+			labels := prometheus.Labels{"command": tc.command}
+			assert.Equal(t, tc.expRequestSize, metricstest.HistogramSum(t, registry, "app_redis_request_size_bytes", labels))
+			metricstest.AssertHistogramCount(t, registry, "app_redis_reply_size_bytes", labels, tc.expReplies)
+			if tc.expReplies > 0 {
+				assert.Equal(t, tc.expReplySize, metricstest.HistogramSum(t, registry, "app_redis_reply_size_bytes", labels))
+			}
+		})
+	}
+}
+
+func TestRedisRecorderSizeBuckets(t *testing.T) {
+	registry := prometheus.NewRegistry()
+	recorder := redismetrics.NewRedisRecorder("test-app", redismetrics.Config{
+		Options:     metrics.Options{Registerer: registry},
+		SizeBuckets: []float64{100, 1000},
+	})
+
+	client := newFakeRedis(t, map[string]string{"app/users/1": "alice"}, nil)
+	client.AddHook(recorder.NewCollectHook())
+	client.Get("app/users/1")
+
+	family, ok := metricstest.Gather(t, registry)["app_redis_reply_size_bytes"]
+	require.True(t, ok)
+	require.Len(t, family.GetMetric(), 1)
+
+	buckets := family.GetMetric()[0].GetHistogram().GetBucket()
+	require.Len(t, buckets, 2)
+	assert.Equal(t, float64(100), buckets[0].GetUpperBound())
+	assert.Equal(t, uint64(1), buckets[0].GetCumulativeCount())
+}
+
+func TestCollectHookStructuredReplySizes(t *testing.T) {
+	bulk := func(v string) string { return fmt.Sprintf("$%d\r\n%s\r\n", len(v), v) }
+
+	replies := map[string]string{
+		"sismember":   ":1\r\n",
+		"incrbyfloat": bulk("10.5"),
+		"zrange":      "*4\r\n" + bulk("alice") + bulk("1.5") + bulk("bob") + bulk("20"),
+		"xrange":      "*1\r\n*2\r\n" + bulk("1-0") + "*2\r\n" + bulk("name") + bulk("alice"),
+		"scan":        "*2\r\n" + bulk("17") + "*2\r\n" + bulk("app/users/1") + bulk("app/users/2"),
+	}
+	dialer := newFakeDialer(func(_ string, args []string) string {
+		if r, ok := replies[strings.ToLower(args[0])]; ok {
+			return r
+		}
+		return "-ERR unknown command\r\n"
+	})
+
+	testCases := []struct {
+		name         string
+		run          func(client *redis.Client)
+		command      string
+		expReplySize float64
+	}{
+		{
+			name:         "Sizes of booleans should be estimated.",
+			run:          func(client *redis.Client) { client.SIsMember("app/admins", "alice") },
+			command:      "sismember",
+			expReplySize: 1,
+		},
+		{
+			name:         "Sizes of floats should be estimated.",
+			run:          func(client *redis.Client) { client.IncrByFloat("app/balance", 0.5) },
+			command:      "incrbyfloat",
+			expReplySize: 4,
+		},
+		{
+			name: "Sizes of sorted set members with scores should be estimated.",
+			run: func(client *redis.Client) {
+				client.ZRangeWithScores("app/scores", 0, -1)
+			},
+			command:      "zrange",
+			expReplySize: 13,
+		},
+		{
+			name:         "Sizes of stream messages should be estimated.",
+			run:          func(client *redis.Client) { client.XRange("app/events", "-", "+") },
+			command:      "xrange",
+			expReplySize: 12,
+		},
+		{
+			name:         "Sizes of scanned keys should be estimated.",
+			run:          func(client *redis.Client) { client.Scan(0, "app/users/*", 10) },
+			command:      "scan",
+			expReplySize: 24,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			registry := prometheus.NewRegistry()
+			recorder := redismetrics.NewRedisRecorder("test-app", redismetrics.Config{Options: metrics.Options{Registerer: registry}})
+
+			client := redis.NewClient(&redis.Options{Dialer: dialer})
+			defer func() { _ = client.Close() }()
+			client.AddHook(recorder.NewCollectHook())
+			tc.run(client)
+
+			labels := prometheus.Labels{"command": tc.command}
+			metricstest.AssertHistogramCount(t, registry, "app_redis_reply_size_bytes", labels, 1)
+			assert.Equal(t, tc.expReplySize, metricstest.HistogramSum(t, registry, "app_redis_reply_size_bytes", labels))
+		})
+	}
+}
+
+// unknownCmd is a command which reply has no known type.
+type unknownCmd struct{}
+
+func (unknownCmd) Name() string        { return "custom" }
+func (unknownCmd) Args() []interface{} { return []interface{}{"custom"} }
+func (unknownCmd) Err() error          { return nil }
+
+func TestObserverUnknownReplySize(t *testing.T) {
+	recorder := metricstest.NewRedisRecorder()
+	redismetrics.NewObserver(recorder).ObserveCommand(unknownCmd{}, time.Millisecond)
+
+	// Replies of unknown types should not be measured.
+	calls := recorder.Calls()
+	require.Len(t, calls, 1)
+	assert.Equal(t, -1, calls[0].Props.ReplySize)
+}
+
+// memberCmd is a command which reply has a structured type unknown to observer.
+type memberCmd struct {
+	unknownCmd
+}
+
+func (memberCmd) Val() struct {
+	Member string
+	Score  float64
+} {
+	return struct {
+		Member string
+		Score  float64
+	}{Member: "alice", Score: 1.5}
+}
+
+func TestObserverStructuredReplySize(t *testing.T) {
+	recorder := metricstest.NewRedisRecorder()
+	observer := redismetrics.NewObserver(recorder)
+
+	// Val method should be looked up once for each type and reused for the following replies.
+	observer.ObserveCommand(memberCmd{}, time.Millisecond)
+	observer.ObserveCommand(memberCmd{}, time.Millisecond)
+	observer.ObserveCommand(unknownCmd{}, time.Millisecond)
+
+	calls := recorder.Calls()
+	require.Len(t, calls, 3)
+	assert.Equal(t, 8, calls[0].Props.ReplySize)
+	assert.Equal(t, 8, calls[1].Props.ReplySize)
+	assert.Equal(t, -1, calls[2].Props.ReplySize)
+}
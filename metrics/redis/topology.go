@@ -0,0 +1,48 @@
+package redis
+
+import (
+	"github.com/go-redis/redis/v7"
+	"github.com/weaponry/go-instrumenting/metrics"
+	"io"
+)
+
+// Values of the event label, redirects are labelled with 'moved' and 'ask' statuses.
+const (
+	eventSlotsReload = "slots_reload"
+	eventFailover    = "failover"
+)
+
+// NewClusterNodeClientFunc returns function creating clients of cluster nodes with hooks passing properties of
+// commands served by the node to recorder, it should be used as NewClient of redis.ClusterOptions instead of adding
+// the hook to the cluster client. Commands redirected by MOVED/ASK are recorded by both nodes, reloads of cluster slots
+// are recorded only when slots are requested from nodes (ClusterSlots of options is not set). Clients are created by
+// newClient, by default redis.NewClient.
+func NewClusterNodeClientFunc(recorder metrics.RedisRecorder, newClient func(opt *redis.Options) *redis.Client) func(opt *redis.Options) *redis.Client {
+	if newClient == nil {
+		newClient = redis.NewClient
+	}
+
+	observer := NewObserver(recorder)
+
+	return func(opt *redis.Options) *redis.Client {
+		client := newClient(opt)
+		client.AddHook(&CollectHook{observer: observer.ForNode(opt.Addr)})
+		return client
+	}
+}
+
+// WatchFailovers subscribes to '+switch-master' messages of the sentinel and passes failovers of the master with
+// passed name to recorder, failovers of all masters are passed if the name is empty. Watching is stopped by closing
+// returned subscription.
+func WatchFailovers(sentinel *redis.SentinelClient, masterName string, recorder metrics.RedisRecorder) io.Closer {
+	observer := NewObserver(recorder)
+	pubsub := sentinel.Subscribe("+switch-master")
+
+	go func() {
+		for msg := range pubsub.Channel() {
+			observer.ObserveSwitchMaster(masterName, msg.Payload)
+		}
+	}()
+
+	return pubsub
+}
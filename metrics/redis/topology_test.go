@@ -0,0 +1,145 @@
+package redis_test
+
+import (
+	"github.com/go-redis/redis/v7"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/weaponry/go-instrumenting/metrics"
+	"github.com/weaponry/go-instrumenting/metrics/metricstest"
+	redismetrics "github.com/weaponry/go-instrumenting/metrics/redis"
+	"testing"
+	"time"
+)
+
+func TestNewClusterNodeClientFunc(t *testing.T) {
+	registry := prometheus.NewRegistry()
+	recorder := redismetrics.NewRedisRecorder("test-app", redismetrics.Config{
+		Options:   metrics.Options{Registerer: registry},
+		NodeLabel: true,
+	})
+
+	newClient := redismetrics.NewClusterNodeClientFunc(recorder, nil)
+	client := newClient(&redis.Options{
+		Addr:   "10.0.0.1:6379",
+		Dialer: fakeRedisDialer(map[string]string{"app/users/1": "alice"}, nil),
+	})
+	defer func() { _ = client.Close() }()
+
+	require.NoError(t, client.Get("app/users/1").Err())
+	_, err := client.Pipelined(func(pipe redis.Pipeliner) error {
+		pipe.Get("app/users/1")
+		return nil
+	})
+	require.NoError(t, err)
+
+	metricstest.AssertCounter(t, registry, "app_redis_requests_total", prometheus.Labels{"node": "10.0.0.1:6379", "pipeline": "none"}, 1)
+	metricstest.AssertCounter(t, registry, "app_redis_requests_total", prometheus.Labels{"node": "10.0.0.1:6379", "pipeline": "pipeline"}, 1)
+	metricstest.AssertHistogramCount(t, registry, "app_redis_pipeline_duration_seconds", prometheus.Labels{"node": "10.0.0.1:6379"}, 1)
+}
+
+func TestObserverTopology(t *testing.T) {
+	moved := redis.NewStringCmd("get", "app/users/1")
+	moved.SetErr(redisError("MOVED 3999 10.0.0.2:6379"))
+	ask := redis.NewStringCmd("get", "app/users/1")
+	ask.SetErr(redisError("ASK 3999 10.0.0.2:6379"))
+	slots := redis.NewClusterSlotsCmd("cluster", "slots")
+	found := redis.NewStringCmd("get", "app/users/1")
+
+	testCases := []struct {
+		name      string
+		node      string
+		expEvents []metrics.RedisTopologyProperties
+	}{
+		{
+			name: "Redirects and reloads of cluster slots should be recorded for nodes.",
+			node: "10.0.0.1:6379",
+			expEvents: []metrics.RedisTopologyProperties{
+				{Node: "10.0.0.1:6379", Event: "moved"},
+				{Node: "10.0.0.1:6379", Event: "ask"},
+				{Node: "10.0.0.1:6379", Event: "slots_reload"},
+			},
+		},
+		{
+			name: "Topology events should not be recorded without nodes.",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			recorder := metricstest.NewRedisRecorder()
+			observer := redismetrics.NewObserver(recorder)
+			if tc.node != "" {
+				observer = observer.ForNode(tc.node)
+			}
+
+			for _, cmd := range []redis.Cmder{moved, ask, slots, found} {
+				observer.ObserveCommand(cmd, time.Millisecond)
+			}
+
+			assert.Equal(t, tc.expEvents, recorder.TopologyEvents())
+			for _, call := range recorder.Calls() {
+				assert.Equal(t, tc.node, call.Props.Node)
+			}
+		})
+	}
+}
+
+func TestObserverSwitchMaster(t *testing.T) {
+	testCases := []struct {
+		name       string
+		masterName string
+		payload    string
+		expEvents  []metrics.RedisTopologyProperties
+	}{
+		{
+			name:       "Failovers should be recorded for new masters.",
+			masterName: "mymaster",
+			payload:    "mymaster 10.0.0.1 6379 10.0.0.2 6379",
+			expEvents:  []metrics.RedisTopologyProperties{{Node: "10.0.0.2:6379", Event: "failover"}},
+		},
+		{
+			name:      "Failovers of all masters should be recorded without master name.",
+			payload:   "other 10.0.0.1 6379 10.0.0.2 6379",
+			expEvents: []metrics.RedisTopologyProperties{{Node: "10.0.0.2:6379", Event: "failover"}},
+		},
+		{
+			name:       "Failovers of other masters should be ignored.",
+			masterName: "mymaster",
+			payload:    "other 10.0.0.1 6379 10.0.0.2 6379",
+		},
+		{
+			name:    "Malformed messages should be ignored.",
+			payload: "mymaster 10.0.0.2",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			recorder := metricstest.NewRedisRecorder()
+			redismetrics.NewObserver(recorder).ObserveSwitchMaster(tc.masterName, tc.payload)
+
+			assert.Equal(t, tc.expEvents, recorder.TopologyEvents())
+		})
+	}
+}
+
+func TestWatchFailovers(t *testing.T) {
+	registry := prometheus.NewRegistry()
+	recorder := redismetrics.NewRedisRecorder("test-app", redismetrics.Config{Options: metrics.Options{Registerer: registry}})
+
+	sentinel := redis.NewSentinelClient(&redis.Options{
+		Dialer: fakeRedisDialer(map[string]string{"+switch-master": "mymaster 10.0.0.1 6379 10.0.0.2 6379"}, nil),
+	})
+	defer func() { _ = sentinel.Close() }()
+
+	watcher := redismetrics.WatchFailovers(sentinel, "mymaster", recorder)
+	defer func() { _ = watcher.Close() }()
+
+	require.Eventually(t, func() bool {
+		return metricstest.CounterValue(t, registry, "app_redis_topology_events_total", prometheus.Labels{
+			"node":  "10.0.0.2:6379",
+			"event": "failover",
+		}) == 1
+	}, time.Second, 10*time.Millisecond)
+}
@@ -0,0 +1,175 @@
+package metrics
+
+import (
+	"errors"
+	"fmt"
+	"github.com/prometheus/client_golang/prometheus"
+	"sync"
+)
+
+// registration is a collector registered in the registerer.
+type registration struct {
+	reg       prometheus.Registerer
+	collector prometheus.Collector
+}
+
+var (
+	refsMu sync.Mutex
+	// refs are the numbers of users of collectors registered by Register, collectors are unregistered by Unregister
+	// when they are not used anymore.
+	refs = make(map[registration]int)
+	// buckets are the buckets of histograms registered by Register with Histogram.
+	buckets = make(map[registration][]float64)
+)
+
+// histogram is a histogram passed to Register with its buckets.
+type histogram struct {
+	vec     **prometheus.HistogramVec
+	buckets []float64
+}
+
+// Histogram wraps pointer to histogram created with passed buckets for Register, which fails to share the histogram
+// with another one registered under the same name with different buckets.
+func Histogram(vec **prometheus.HistogramVec, buckets []float64) interface{} {
+	return histogram{vec: vec, buckets: buckets}
+}
+
+// Register registers collectors in reg. Collectors should be passed as pointers to *prometheus.CounterVec,
+// *prometheus.GaugeVec, *prometheus.HistogramVec or prometheus.Collector variables. If an equal collector has been
+// already registered, the variable is replaced with the existing collector, hence recorders created with the same
+// configuration share their metrics. Histograms wrapped with Histogram are shared only when they have equal buckets,
+// buckets of other histograms are not compared. Shared collectors are unregistered by Unregister of their last user,
+// collectors registered by other means are never unregistered. On failure, collectors acquired by the call are
+// released.
+func Register(reg prometheus.Registerer, collectors ...interface{}) error {
+	refsMu.Lock()
+	defer refsMu.Unlock()
+
+	var acquired []prometheus.Collector
+
+	for _, c := range collectors {
+		collector, bounds, replace := unpackCollector(c)
+
+		err := reg.Register(collector)
+		if err == nil {
+			acquire(reg, collector, false)
+			if bounds != nil {
+				buckets[registration{reg: reg, collector: collector}] = bounds
+			}
+			acquired = append(acquired, collector)
+			continue
+		}
+
+		var are prometheus.AlreadyRegisteredError
+		if errors.As(err, &are) {
+			existing := buckets[registration{reg: reg, collector: are.ExistingCollector}]
+			switch {
+			case bounds != nil && existing != nil && !equalBuckets(bounds, existing):
+				err = fmt.Errorf("histogram has been already registered with buckets %v instead of %v", existing, bounds)
+			case replace(are.ExistingCollector):
+				acquire(reg, are.ExistingCollector, true)
+				acquired = append(acquired, are.ExistingCollector)
+				continue
+			default:
+				err = fmt.Errorf("collector of type %T has been already registered instead of %T", are.ExistingCollector, collector)
+			}
+		}
+
+		release(reg, acquired)
+		return err
+	}
+
+	return nil
+}
+
+// Unregister releases collectors registered in reg by Register, collectors are unregistered when all their users have
+// released them. Each successful call of Register should be matched with a single call of Unregister.
+func Unregister(reg prometheus.Registerer, collectors ...prometheus.Collector) {
+	refsMu.Lock()
+	defer refsMu.Unlock()
+
+	release(reg, collectors)
+}
+
+// release forgets a user of collectors and unregisters collectors which are not used anymore.
+func release(reg prometheus.Registerer, collectors []prometheus.Collector) {
+	for _, collector := range collectors {
+		key := registration{reg: reg, collector: collector}
+		if refs[key] == 0 {
+			continue
+		}
+
+		refs[key]--
+		if refs[key] == 0 {
+			delete(refs, key)
+			delete(buckets, key)
+			reg.Unregister(collector)
+		}
+	}
+}
+
+// acquire counts a new user of the collector. Existing collectors which have been registered by other means get an
+// extra user, hence they are never unregistered by Unregister.
+func acquire(reg prometheus.Registerer, collector prometheus.Collector, existing bool) {
+	key := registration{reg: reg, collector: collector}
+	if existing && refs[key] == 0 {
+		refs[key] = 1
+	}
+	refs[key]++
+}
+
+// equalBuckets reports whether a and b are the same buckets.
+func equalBuckets(a, b []float64) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// unpackCollector returns collector pointed by c, buckets of histogram wrapped with Histogram and function which
+// replaces the collector with existing collector of the same type.
+func unpackCollector(c interface{}) (prometheus.Collector, []float64, func(prometheus.Collector) bool) {
+	if h, ok := c.(histogram); ok {
+		collector, _, replace := unpackCollector(h.vec)
+		return collector, h.buckets, replace
+	}
+
+	switch v := c.(type) {
+	case **prometheus.CounterVec:
+		return *v, nil, func(existing prometheus.Collector) bool {
+			e, ok := existing.(*prometheus.CounterVec)
+			if ok {
+				*v = e
+			}
+			return ok
+		}
+	case **prometheus.GaugeVec:
+		return *v, nil, func(existing prometheus.Collector) bool {
+			e, ok := existing.(*prometheus.GaugeVec)
+			if ok {
+				*v = e
+			}
+			return ok
+		}
+	case **prometheus.HistogramVec:
+		return *v, nil, func(existing prometheus.Collector) bool {
+			e, ok := existing.(*prometheus.HistogramVec)
+			if ok {
+				*v = e
+			}
+			return ok
+		}
+	case *prometheus.Collector:
+		return *v, nil, func(existing prometheus.Collector) bool {
+			*v = existing
+			return true
+		}
+	default:
+		panic(fmt.Sprintf("metrics: unsupported collector type %T", c))
+	}
+}
@@ -0,0 +1,88 @@
+package metrics_test
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/weaponry/go-instrumenting/metrics"
+	"testing"
+)
+
+func newCounterVec() *prometheus.CounterVec {
+	return prometheus.NewCounterVec(prometheus.CounterOpts{Name: "requests_total", Help: "Requests."}, []string{"code"})
+}
+
+// registered reports whether a collector equal to c is registered in reg.
+func registered(reg prometheus.Registerer, c prometheus.Collector) bool {
+	err := reg.Register(c)
+	if err == nil {
+		reg.Unregister(c)
+	}
+	_, ok := err.(prometheus.AlreadyRegisteredError)
+	return ok
+}
+
+func TestRegister(t *testing.T) {
+	registry := prometheus.NewRegistry()
+
+	first, second := newCounterVec(), newCounterVec()
+	require.NoError(t, metrics.Register(registry, &first))
+	require.NoError(t, metrics.Register(registry, &second))
+	assert.Same(t, first, second, "equal collectors should be shared")
+
+	// Shared collectors should be unregistered by their last user.
+	metrics.Unregister(registry, first)
+	assert.True(t, registered(registry, newCounterVec()))
+	metrics.Unregister(registry, second)
+	assert.False(t, registered(registry, newCounterVec()))
+}
+
+func TestRegisterExisting(t *testing.T) {
+	registry := prometheus.NewRegistry()
+
+	// Collectors registered by other means should never be unregistered.
+	existing := newCounterVec()
+	registry.MustRegister(existing)
+
+	c := newCounterVec()
+	require.NoError(t, metrics.Register(registry, &c))
+	assert.Same(t, existing, c)
+
+	metrics.Unregister(registry, c)
+	assert.True(t, registered(registry, newCounterVec()))
+}
+
+func TestRegisterFailure(t *testing.T) {
+	registry := prometheus.NewRegistry()
+	registry.MustRegister(prometheus.NewGauge(prometheus.GaugeOpts{Name: "in_flight", Help: "Requests in flight."}))
+
+	c := newCounterVec()
+	g := prometheus.NewGaugeVec(prometheus.GaugeOpts{Name: "in_flight", Help: "Requests in flight."}, nil)
+
+	// Collectors registered by the failed call should be unregistered.
+	assert.Error(t, metrics.Register(registry, &c, &g))
+	assert.False(t, registered(registry, newCounterVec()))
+}
+
+func TestRegisterHistogramBuckets(t *testing.T) {
+	registry := prometheus.NewRegistry()
+
+	newHistogramVec := func(buckets []float64) *prometheus.HistogramVec {
+		return prometheus.NewHistogramVec(prometheus.HistogramOpts{Name: "duration_seconds", Help: "Durations.", Buckets: buckets}, []string{"code"})
+	}
+
+	first, second := newHistogramVec([]float64{1, 2}), newHistogramVec([]float64{1, 2})
+	require.NoError(t, metrics.Register(registry, metrics.Histogram(&first, []float64{1, 2})))
+	require.NoError(t, metrics.Register(registry, metrics.Histogram(&second, []float64{1, 2})))
+	assert.Same(t, first, second, "histograms with equal buckets should be shared")
+
+	// Histograms with different buckets should not be shared.
+	third := newHistogramVec([]float64{1, 5})
+	assert.Error(t, metrics.Register(registry, metrics.Histogram(&third, []float64{1, 5})))
+	assert.NotSame(t, first, third)
+
+	// Buckets should be forgotten when the histogram is unregistered.
+	metrics.Unregister(registry, first)
+	metrics.Unregister(registry, second)
+	require.NoError(t, metrics.Register(registry, metrics.Histogram(&third, []float64{1, 5})))
+}